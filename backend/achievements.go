@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"2048game/engine"
+)
+
+// Achievement is a badge a player earns the first time one of their games
+// meets all its conditions. Achievements are JSON or YAML files in
+// ACHIEVEMENTS_DIR, so new ones don't need code changes.
+type Achievement struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	When        AchievementRule `json:"when"`
+}
+
+// AchievementRule lists the conditions of an achievement. Every condition
+// that is set must hold. Game conditions are checked against the game being
+// played, history conditions against the player's finished games.
+type AchievementRule struct {
+	// Game conditions
+	Mode        string `json:"mode,omitempty"`        // the game's mode, "classic" unless set (see met)
+	Tile        int    `json:"tile,omitempty"`        // a tile at least this high was reached
+	WithinMoves int    `json:"withinMoves,omitempty"` // ...within this many moves
+	Score       int    `json:"score,omitempty"`
+	Won         bool   `json:"won,omitempty"`
+	Finished    bool   `json:"finished,omitempty"` // the game is over
+
+	// History conditions
+	Games      int `json:"games,omitempty"`      // finished games
+	Wins       int `json:"wins,omitempty"`       // finished games that were won
+	StreakDays int `json:"streakDays,omitempty"` // consecutive days (UTC) with a finished game
+}
+
+// Award is an achievement a player has earned
+type Award struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	GameID      string    `json:"gameId"`
+	AwardedAt   time.Time `json:"awardedAt"`
+}
+
+// PlayerProfile is a player's history of finished games and their awards
+type PlayerProfile struct {
+	Games      int     `json:"games"`
+	Wins       int     `json:"wins"`
+	LastPlayed string  `json:"lastPlayed,omitempty"` // the day (UTC) of the last finished game
+	Streak     int     `json:"streak"`               // consecutive days up to LastPlayed
+	Awards     []Award `json:"awards"`
+	// IDs of the last few finished games, so a game that two requests both
+	// see end is only counted once
+	Finished []string `json:"finished,omitempty"`
+}
+
+// maxFinishedIDs is how many finished game IDs a profile remembers
+const maxFinishedIDs = 20
+
+// achievements holds every loaded achievement, sorted by ID. It is filled
+// once at startup.
+var achievements []*Achievement
+
+// achievementEvents hands new awards to a player's achievement streams on
+// this pod
+var achievementEvents = newAwardBroker()
+
+var errNoNewAwards = errors.New("no new awards")
+
+func playerProfileKey(playerID string) string {
+	return "player#" + playerID
+}
+
+// initAchievements loads the achievement definitions from ACHIEVEMENTS_DIR
+// (default "achievements"). A broken file stops the server, like a broken
+// level.
+func initAchievements() {
+	dir := os.Getenv("ACHIEVEMENTS_DIR")
+	if dir == "" {
+		dir = "achievements"
+	}
+
+	loaded, err := loadAchievements(dir)
+	if err != nil {
+		slog.Error("failed to load achievements", "dir", dir, "error", err)
+		os.Exit(1)
+	}
+	achievements = loaded
+
+	ids := make([]string, len(loaded))
+	for i, a := range loaded {
+		ids[i] = a.ID
+	}
+	slog.Info("achievements loaded", "dir", dir, "achievements", ids)
+}
+
+// loadAchievements reads every .json, .yaml and .yml file in dir. A missing
+// directory means no achievements.
+func loadAchievements(dir string) ([]*Achievement, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []*Achievement
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		var a Achievement
+		if err := readDataFile(path, &a); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if a.ID == "" {
+			a.ID = strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		}
+		if err := a.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if seen[a.ID] {
+			return nil, fmt.Errorf("%s: duplicate achievement id %q", path, a.ID)
+		}
+		seen[a.ID] = true
+		loaded = append(loaded, &a)
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].ID < loaded[j].ID })
+	return loaded, nil
+}
+
+// Validate reports whether a can be awarded
+func (a *Achievement) Validate() error {
+	// Achievement IDs follow the same pattern as level IDs
+	if !levelIDPattern.MatchString(a.ID) {
+		return fmt.Errorf("invalid achievement id %q", a.ID)
+	}
+	if a.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	w := a.When
+	if w.Tile < 0 || w.WithinMoves < 0 || w.Score < 0 || w.Games < 0 || w.Wins < 0 || w.StreakDays < 0 {
+		return fmt.Errorf("conditions can't be negative")
+	}
+	if w.WithinMoves > 0 && w.Tile == 0 {
+		return fmt.Errorf("withinMoves needs a tile")
+	}
+	if w == (AchievementRule{Mode: w.Mode}) {
+		return fmt.Errorf("at least one condition besides mode is required")
+	}
+	return nil
+}
+
+// met reports whether game, played by a player with profile p, meets r. A
+// rule on the game's tiles, score or win only counts classic games unless it
+// names a mode, as custom spawns and levels' starting tiles make those easy.
+func (r AchievementRule) met(game *GameState, p *PlayerProfile) bool {
+	mode := r.Mode
+	if mode == "" && (r.Tile > 0 || r.Score > 0 || r.Won) {
+		mode = engine.VariantClassic
+	}
+	if mode != "" && gameMode(game) != mode {
+		return false
+	}
+	if r.Tile > 0 && !reachedTile(game, r.Tile, r.WithinMoves) {
+		return false
+	}
+	if game.Score < r.Score || (r.Won && !game.Won) || (r.Finished && !game.GameOver) {
+		return false
+	}
+	return p.Games >= r.Games && p.Wins >= r.Wins && p.Streak >= r.StreakDays
+}
+
+// reachedTile reports whether game reached a tile of at least tile, within
+// withinMoves moves unless that is zero
+func reachedTile(game *GameState, tile, withinMoves int) bool {
+	for t, moves := range game.Reached {
+		if t >= tile && (withinMoves == 0 || moves <= withinMoves) {
+			return true
+		}
+	}
+	// Games started before tiles were tracked only know their board
+	return withinMoves == 0 && game.Board.MaxTile() >= tile
+}
+
+// noteMaxTile records the move on which game first reached its current
+// highest tile, reporting whether that tile is new
+func (game *GameState) noteMaxTile() bool {
+	tile := game.Board.MaxTile()
+	if _, ok := game.Reached[tile]; ok {
+		return false
+	}
+	if game.Reached == nil {
+		game.Reached = make(map[int]int)
+	}
+	game.Reached[tile] = game.Moves
+	return true
+}
+
+// finishGame adds a finished game, played on day, to p's history, unless it
+// is there already. It reports whether it did.
+func (p *PlayerProfile) finishGame(game *GameState, day time.Time) bool {
+	for _, id := range p.Finished {
+		if id == game.ID {
+			return false
+		}
+	}
+	p.Finished = append(p.Finished, game.ID)
+	if len(p.Finished) > maxFinishedIDs {
+		p.Finished = p.Finished[len(p.Finished)-maxFinishedIDs:]
+	}
+
+	p.Games++
+	if game.Won {
+		p.Wins++
+	}
+
+	today := day.UTC().Format(time.DateOnly)
+	switch p.LastPlayed {
+	case today:
+	case day.UTC().AddDate(0, 0, -1).Format(time.DateOnly):
+		p.Streak++
+	default:
+		p.Streak = 1
+	}
+	p.LastPlayed = today
+	return true
+}
+
+// hasAward reports whether p has earned the achievement id
+func (p *PlayerProfile) hasAward(id string) bool {
+	for _, award := range p.Awards {
+		if award.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// awardAchievements checks game, which has just reached a new tile or
+// finished, against every achievement its player hasn't earned yet, and
+// returns the new awards. finished adds the game to the player's history.
+// Failures are logged; the move itself has already been saved.
+func awardAchievements(ctx context.Context, game *GameState, finished bool) []Award {
+	if game.PlayerID == "" || (len(achievements) == 0 && !finished) {
+		return nil
+	}
+
+	now := time.Now()
+	var awarded []Award
+	_, err := updateRecord(ctx, playerProfileKey(game.PlayerID), 0, func(p *PlayerProfile, _ bool) error {
+		awarded = nil
+		counted := finished && p.finishGame(game, now)
+		for _, a := range achievements {
+			if p.hasAward(a.ID) || !a.When.met(game, p) {
+				continue
+			}
+			award := Award{ID: a.ID, Name: a.Name, Description: a.Description, GameID: game.ID, AwardedAt: now}
+			p.Awards = append(p.Awards, award)
+			awarded = append(awarded, award)
+		}
+		if !counted && len(awarded) == 0 {
+			return errNoNewAwards
+		}
+		return nil
+	})
+	if errors.Is(err, errNoNewAwards) {
+		return nil
+	}
+
+	logger := loggerFromContext(ctx)
+	if err != nil {
+		logger.Error("failed to update player achievements", "game_id", game.ID, "player_id", game.PlayerID, "error", err)
+		return nil
+	}
+	for _, award := range awarded {
+		logger.Info("achievement awarded", "game_id", game.ID, "player_id", game.PlayerID, "achievement", award.ID)
+	}
+	if len(awarded) > 0 {
+		achievementEvents.Publish(game.PlayerID, awarded)
+	}
+	return awarded
+}
+
+// awardBroker pushes awards to local achievement streams as they are made, so
+// the streams don't have to poll storage. Like eventBroker it only reaches
+// this pod.
+type awardBroker struct {
+	mu   sync.Mutex
+	subs map[string]map[*awardSubscriber]bool
+}
+
+// awardSubscriber is one achievement stream. Awards wait in pending until the
+// stream takes them; wake holds at most one pending wake-up.
+type awardSubscriber struct {
+	wake    chan struct{}
+	pending []Award
+}
+
+func newAwardBroker() *awardBroker {
+	return &awardBroker{subs: make(map[string]map[*awardSubscriber]bool)}
+}
+
+// Subscribe registers a stream for playerID's awards, and returns a function
+// to unsubscribe
+func (b *awardBroker) Subscribe(playerID string) (*awardSubscriber, func()) {
+	sub := &awardSubscriber{wake: make(chan struct{}, 1)}
+
+	b.mu.Lock()
+	if b.subs[playerID] == nil {
+		b.subs[playerID] = make(map[*awardSubscriber]bool)
+	}
+	b.subs[playerID][sub] = true
+	b.mu.Unlock()
+
+	return sub, func() {
+		b.mu.Lock()
+		delete(b.subs[playerID], sub)
+		if len(b.subs[playerID]) == 0 {
+			delete(b.subs, playerID)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Publish queues awards for every stream of playerID and wakes them
+func (b *awardBroker) Publish(playerID string, awards []Award) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs[playerID] {
+		sub.pending = append(sub.pending, awards...)
+		select {
+		case sub.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Take returns and clears the awards waiting for sub
+func (b *awardBroker) Take(sub *awardSubscriber) []Award {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	awards := sub.pending
+	sub.pending = nil
+	return awards
+}
+
+// Achievement handlers
+
+// playerHandler serves /player/{id}/achievements and
+// /player/{id}/achievements/events
+func playerHandler(w http.ResponseWriter, r *http.Request) {
+	playerID, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/player/"), "/")
+	if playerID == "" {
+		http.NotFound(w, r)
+		return
+	}
+	switch rest {
+	case "achievements":
+		playerAchievementsHandler(w, r, playerID)
+	case "achievements/events":
+		achievementEventsHandler(w, r, playerID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// playerAchievementsHandler returns the achievements a player has earned and
+// the ones still to earn
+func playerAchievementsHandler(w http.ResponseWriter, r *http.Request, playerID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var profile PlayerProfile
+	if _, err := loadRecord(r.Context(), playerProfileKey(playerID), &profile); err != nil {
+		loggerFromContext(r.Context()).Error("failed to load player profile", "player_id", playerID, "error", err)
+		http.Error(w, "Failed to load achievements", http.StatusInternalServerError)
+		return
+	}
+
+	locked := make([]*Achievement, 0, len(achievements))
+	for _, a := range achievements {
+		if !profile.hasAward(a.ID) {
+			locked = append(locked, a)
+		}
+	}
+	if profile.Awards == nil {
+		profile.Awards = []Award{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"playerId": playerID,
+		"games":    profile.Games,
+		"wins":     profile.Wins,
+		"streak":   profile.Streak,
+		"earned":   profile.Awards,
+		"locked":   locked,
+	})
+}
+
+// achievementEventsHandler streams an "achievement" event for each
+// achievement the player earns on this pod while connected. Awards are pushed
+// from awardAchievements, so an idle stream costs no reads.
+func achievementEventsHandler(w http.ResponseWriter, r *http.Request, playerID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+
+	sub, unsubscribe := achievementEvents.Subscribe(playerID)
+	defer unsubscribe()
+
+	flusher, ok := startSSE(w)
+	if !ok {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-serverClosing:
+			return
+		case <-heartbeat.C:
+			if err := writeSSEHeartbeat(w, flusher); err != nil {
+				return
+			}
+			continue
+		case <-sub.wake:
+		}
+
+		for _, award := range achievementEvents.Take(sub) {
+			if err := writeSSE(w, flusher, "achievement", award); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"2048game/engine"
+)
+
+func TestLoadAchievements(t *testing.T) {
+	loaded, err := loadAchievements("achievements")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("no achievements loaded")
+	}
+	for i := 1; i < len(loaded); i++ {
+		if loaded[i-1].ID >= loaded[i].ID {
+			t.Errorf("achievements not sorted by id: %q before %q", loaded[i-1].ID, loaded[i].ID)
+		}
+	}
+}
+
+func TestAchievementValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Achievement
+		ok   bool
+	}{
+		{"tile", Achievement{ID: "a", Name: "A", When: AchievementRule{Tile: 2048}}, true},
+		{"bad id", Achievement{ID: "A B", Name: "A", When: AchievementRule{Tile: 2048}}, false},
+		{"no name", Achievement{ID: "a", When: AchievementRule{Tile: 2048}}, false},
+		{"negative", Achievement{ID: "a", Name: "A", When: AchievementRule{Score: -1}}, false},
+		{"moves without tile", Achievement{ID: "a", Name: "A", When: AchievementRule{WithinMoves: 10}}, false},
+		{"only mode", Achievement{ID: "a", Name: "A", When: AchievementRule{Mode: "classic"}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.a.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestAchievementRuleMet(t *testing.T) {
+	game := func(rules engine.Rules, tile, moves int) *GameState {
+		g := newGameState(rules)
+		g.Board = engine.Board{}
+		g.Board[0][0] = engine.Tile(tile)
+		g.Moves = moves
+		g.noteMaxTile()
+		return g
+	}
+	classic := game(engine.Rules{}, 2048, 500)
+	custom := game(engine.Rules{SpawnWeights: []engine.SpawnWeight{{Value: 1024, Weight: 1}}}, 2048, 1)
+	level := game(engine.Rules{}, 2048, 1)
+	level.Level = "pillars"
+
+	tests := []struct {
+		name string
+		rule AchievementRule
+		game *GameState
+		p    PlayerProfile
+		want bool
+	}{
+		{"tile", AchievementRule{Tile: 2048}, classic, PlayerProfile{}, true},
+		{"tile not reached", AchievementRule{Tile: 4096}, classic, PlayerProfile{}, false},
+		{"too many moves", AchievementRule{Tile: 2048, WithinMoves: 299}, classic, PlayerProfile{}, false},
+		{"custom spawns aren't classic", AchievementRule{Tile: 2048}, custom, PlayerProfile{}, false},
+		{"levels aren't classic", AchievementRule{Tile: 2048}, level, PlayerProfile{}, false},
+		{"named mode", AchievementRule{Mode: custom.Rules.Name(), Tile: 2048}, custom, PlayerProfile{}, true},
+		{"history counts any mode", AchievementRule{StreakDays: 3}, custom, PlayerProfile{Streak: 3}, true},
+		{"history not met", AchievementRule{Games: 10}, classic, PlayerProfile{Games: 9}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.met(tt.game, &tt.p); got != tt.want {
+			t.Errorf("%s: met = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFinishGame(t *testing.T) {
+	day := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	var p PlayerProfile
+
+	won := &GameState{ID: "g1"}
+	won.Won = true
+	if !p.finishGame(won, day) {
+		t.Fatal("first finish not counted")
+	}
+	if p.finishGame(won, day) {
+		t.Error("a game finished twice was counted twice")
+	}
+	p.finishGame(&GameState{ID: "g2"}, day.Add(time.Hour))
+	p.finishGame(&GameState{ID: "g3"}, day.AddDate(0, 0, 1))
+	if p.Games != 3 || p.Wins != 1 || p.Streak != 2 {
+		t.Errorf("games %d, wins %d, streak %d; want 3, 1, 2", p.Games, p.Wins, p.Streak)
+	}
+
+	p.finishGame(&GameState{ID: "g4"}, day.AddDate(0, 0, 3))
+	if p.Streak != 1 {
+		t.Errorf("streak after a missed day = %d, want 1", p.Streak)
+	}
+
+	for i := 0; i < 2*maxFinishedIDs; i++ {
+		p.finishGame(&GameState{ID: fmt.Sprintf("many-%d", i)}, day)
+	}
+	if len(p.Finished) > maxFinishedIDs {
+		t.Errorf("profile remembers %d finished games, want at most %d", len(p.Finished), maxFinishedIDs)
+	}
+}
+
+func TestAwardAchievements(t *testing.T) {
+	saved := achievements
+	achievements = []*Achievement{
+		{ID: "finish", Name: "Finish", When: AchievementRule{Finished: true}},
+		{ID: "two-games", Name: "Two Games", When: AchievementRule{Games: 2}},
+	}
+	defer func() { achievements = saved }()
+
+	ctx := context.Background()
+	playerID := "award-test-" + generateToken()
+	sub, unsubscribe := achievementEvents.Subscribe(playerID)
+	defer unsubscribe()
+
+	game := &GameState{ID: "g1", PlayerID: playerID}
+	game.GameOver = true
+	if got := awardAchievements(ctx, game, true); len(got) != 1 || got[0].ID != "finish" {
+		t.Fatalf("first finish awarded %v, want finish", got)
+	}
+	select {
+	case <-sub.wake:
+	default:
+		t.Fatal("stream wasn't woken")
+	}
+	if pushed := achievementEvents.Take(sub); len(pushed) != 1 || pushed[0].ID != "finish" {
+		t.Errorf("stream got %v, want finish", pushed)
+	}
+
+	// The same game ending again, as when a state poll and a move both see a
+	// time-attack game expire, isn't a second game
+	if got := awardAchievements(ctx, game, true); len(got) != 0 {
+		t.Errorf("finishing the game again awarded %v", got)
+	}
+	var profile PlayerProfile
+	if _, err := loadRecord(ctx, playerProfileKey(playerID), &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.Games != 1 {
+		t.Errorf("games = %d after finishing one game twice, want 1", profile.Games)
+	}
+
+	other := &GameState{ID: "g2", PlayerID: playerID}
+	if got := awardAchievements(ctx, other, true); len(got) != 1 || got[0].ID != "two-games" {
+		t.Errorf("second game awarded %v, want two-games", got)
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"2048game/engine"
+)
+
+// adminToken guards the admin API (ADMIN_TOKEN). Admin endpoints don't exist
+// unless it is set.
+var adminToken = os.Getenv("ADMIN_TOKEN")
+
+// withAdmin only lets requests through that carry the admin token as a
+// bearer token
+func withAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			loggerFromContext(r.Context()).Warn("rejected admin request", "path", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// adminRoute wraps an admin handler. Admin endpoints don't send CORS headers,
+// so browsers won't call them from other sites.
+func adminRoute(h http.HandlerFunc) http.HandlerFunc {
+	return withRequestID(withTracing(withAdmin(h)))
+}
+
+// Bans
+
+// playerBansKey is the record holding every banned player, by player ID
+const playerBansKey = "leaderboard#bans"
+
+// PlayerBan stops a player from submitting scores
+type PlayerBan struct {
+	PlayerID string    `json:"playerId"`
+	Reason   string    `json:"reason,omitempty"`
+	BannedAt time.Time `json:"bannedAt"`
+}
+
+var errPlayerNotBanned = errors.New("player is not banned")
+
+// playerBanned reports whether playerID is banned
+func playerBanned(ctx context.Context, playerID string) (bool, error) {
+	var bans map[string]PlayerBan
+	if _, err := loadRecord(ctx, playerBansKey, &bans); err != nil {
+		return false, err
+	}
+	_, banned := bans[playerID]
+	return banned, nil
+}
+
+// DeleteEntry removes the entry id for good. Hiding it with Moderate keeps
+// it for the record instead.
+func (l *Leaderboard) DeleteEntry(ctx context.Context, id string) (LeaderboardEntry, error) {
+	if err := l.loadFromPersistentStorage(ctx); err != nil {
+		return LeaderboardEntry{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	index := -1
+	for i := range l.entries {
+		if l.entries[i].ID == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return LeaderboardEntry{}, errEntryNotFound
+	}
+	entry := l.entries[index]
+
+	if dynamodbClient != nil {
+		if err := deleteEntryFromDynamoDB(ctx, entry, l.otherRuns(entry)); err != nil {
+			return LeaderboardEntry{}, err
+		}
+	}
+	l.entries = append(l.entries[:index], l.entries[index+1:]...)
+	return entry, nil
+}
+
+// otherRuns returns the player's runs in entry's partition other than entry,
+// to find their best without it. The caller holds mu.
+func (l *Leaderboard) otherRuns(entry LeaderboardEntry) []LeaderboardEntry {
+	var runs []LeaderboardEntry
+	for _, e := range l.entries {
+		if e.ID != entry.ID && e.PlayerID == entry.PlayerID && e.Partition == entry.Partition {
+			runs = append(runs, e)
+		}
+	}
+	return runs
+}
+
+// Admin handlers
+
+// decodeAdminRequest decodes the request body into v, writing an error
+// response if the method isn't POST or the body is invalid
+func decodeAdminRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// deleteEntryHandler deletes a leaderboard entry
+func deleteEntryHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+
+	entry, err := globalLeaderboard.DeleteEntry(r.Context(), req.ID)
+	switch {
+	case errors.Is(err, errEntryNotFound):
+		http.Error(w, "Entry not found", http.StatusNotFound)
+		return
+	case err != nil:
+		loggerFromContext(r.Context()).Error("failed to delete entry", "entry_id", req.ID, "error", err)
+		http.Error(w, "Failed to delete entry", http.StatusInternalServerError)
+		return
+	}
+	audit(r, "entry.delete", entry.ID, map[string]any{
+		"playerId": entry.PlayerID,
+		"name":     entry.Name,
+		"score":    entry.Score,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// banPlayerHandler bans a player from submitting scores. Banning a banned
+// player again updates the reason.
+func banPlayerHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PlayerID string `json:"playerId"`
+		Reason   string `json:"reason"`
+	}
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	if req.PlayerID == "" {
+		http.Error(w, "playerId is required", http.StatusBadRequest)
+		return
+	}
+
+	var ban PlayerBan
+	if _, err := updateRecord(r.Context(), playerBansKey, 0, func(bans *map[string]PlayerBan, _ bool) error {
+		if *bans == nil {
+			*bans = make(map[string]PlayerBan)
+		}
+		ban = PlayerBan{PlayerID: req.PlayerID, Reason: req.Reason, BannedAt: time.Now()}
+		if existing, ok := (*bans)[req.PlayerID]; ok {
+			ban.BannedAt = existing.BannedAt
+		}
+		(*bans)[req.PlayerID] = ban
+		return nil
+	}); err != nil {
+		loggerFromContext(r.Context()).Error("failed to ban player", "player_id", req.PlayerID, "error", err)
+		http.Error(w, "Failed to ban player", http.StatusInternalServerError)
+		return
+	}
+	audit(r, "player.ban", req.PlayerID, map[string]any{"reason": req.Reason})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ban)
+}
+
+// unbanPlayerHandler lets a banned player submit scores again
+func unbanPlayerHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PlayerID string `json:"playerId"`
+	}
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+
+	_, err := updateRecord(r.Context(), playerBansKey, 0, func(bans *map[string]PlayerBan, _ bool) error {
+		if _, ok := (*bans)[req.PlayerID]; !ok {
+			return errPlayerNotBanned
+		}
+		delete(*bans, req.PlayerID)
+		return nil
+	})
+	switch {
+	case errors.Is(err, errPlayerNotBanned):
+		http.Error(w, "Player not banned", http.StatusNotFound)
+		return
+	case err != nil:
+		loggerFromContext(r.Context()).Error("failed to unban player", "player_id", req.PlayerID, "error", err)
+		http.Error(w, "Failed to unban player", http.StatusInternalServerError)
+		return
+	}
+	audit(r, "player.unban", req.PlayerID, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// playerBansHandler lists banned players, most recently banned first
+func playerBansHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var bans map[string]PlayerBan
+	if _, err := loadRecord(r.Context(), playerBansKey, &bans); err != nil {
+		loggerFromContext(r.Context()).Error("failed to load bans", "error", err)
+		http.Error(w, "Failed to load bans", http.StatusInternalServerError)
+		return
+	}
+	list := make([]PlayerBan, 0, len(bans))
+	for _, ban := range bans {
+		list = append(list, ban)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].BannedAt.After(list[j].BannedAt) })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bans":  list,
+		"total": len(list),
+	})
+}
+
+// sessionHandler looks up a game session by its id query parameter
+func sessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if dynamodbClient == nil {
+		http.Error(w, "Game sessions need DynamoDB", http.StatusServiceUnavailable)
+		return
+	}
+
+	gameID := r.URL.Query().Get("id")
+	game, err := loadGameSession(r.Context(), gameID)
+	if err != nil {
+		writeSessionLoadError(w, r, gameID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game)
+}
+
+// endSessionHandler force-ends a game session by marking it over, so the
+// player can't move in it. The session is kept for inspection, and a race or
+// tournament it belongs to sees the game finish as usual.
+func endSessionHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	if dynamodbClient == nil {
+		http.Error(w, "Game sessions need DynamoDB", http.StatusServiceUnavailable)
+		return
+	}
+
+	game, err := loadGameSession(r.Context(), req.ID)
+	if err != nil {
+		writeSessionLoadError(w, r, req.ID, err)
+		return
+	}
+	if game.GameOver {
+		http.Error(w, "Game is already over", http.StatusConflict)
+		return
+	}
+	game.GameOver = true
+	if err := saveGameSession(r.Context(), game); err != nil {
+		loggerFromContext(r.Context()).Error("failed to end game session", "game_id", req.ID, "error", err)
+		http.Error(w, "Failed to end game session", http.StatusInternalServerError)
+		return
+	}
+	if game.Race != nil {
+		reportRaceProgress(r.Context(), game)
+	}
+	if game.Tournament != nil {
+		reportTournamentRun(r.Context(), game)
+	}
+	publishToSpectators(game)
+	awardAchievements(r.Context(), game, true)
+	audit(r, "session.end", game.ID, map[string]any{
+		"playerId": game.PlayerID,
+		"score":    game.Score,
+		"moves":    game.Moves,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game)
+}
+
+// refreshStatsHandler recomputes the leaderboard statistics now rather than
+// at the next scheduled refresh
+func refreshStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := refreshStats(r.Context()); err != nil {
+		loggerFromContext(r.Context()).Error("failed to refresh leaderboard statistics", "error", err)
+		http.Error(w, "Failed to refresh statistics", http.StatusInternalServerError)
+		return
+	}
+	stats := cachedStats(leaderboardFilter{BoardSize: engine.Size})
+	audit(r, "stats.refresh", "", map[string]any{"games": stats.TotalGames})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// reloadLeaderboardHandler reloads this pod's leaderboard from storage
+func reloadLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := globalLeaderboard.loadFromPersistentStorage(r.Context()); err != nil {
+		loggerFromContext(r.Context()).Error("failed to reload leaderboard", "error", err)
+		http.Error(w, "Failed to reload leaderboard", http.StatusInternalServerError)
+		return
+	}
+	globalLeaderboard.mu.RLock()
+	entries := len(globalLeaderboard.entries)
+	globalLeaderboard.mu.RUnlock()
+	audit(r, "leaderboard.reload", "", map[string]any{"entries": entries})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+	})
+}
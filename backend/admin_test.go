@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"2048game/engine"
+)
+
+// adminPost calls an admin handler with body as JSON
+func adminPost(h http.HandlerFunc, body any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(data))))
+	return rec
+}
+
+// auditActions returns the actions in today's audit log, newest first
+func auditActions(t *testing.T) []string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	auditLogHandler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var res struct {
+		Events []AuditEvent `json:"events"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("audit log %d: %v", rec.Code, err)
+	}
+	var actions []string
+	for _, e := range res.Events {
+		actions = append(actions, e.Action+" "+e.Target)
+	}
+	return actions
+}
+
+func TestWithAdmin(t *testing.T) {
+	saved := adminToken
+	defer func() { adminToken = saved }()
+	called := false
+	h := withAdmin(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	tests := []struct {
+		token  string
+		header string
+		code   int
+	}{
+		{"", "Bearer ", http.StatusNotFound}, // no admin API without a token
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "secret", http.StatusUnauthorized},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"secret", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		adminToken, called = tt.token, false
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/leaderboard/admin/review", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		h(rec, r)
+		if rec.Code != tt.code || called != (tt.code == http.StatusOK) {
+			t.Errorf("token %q, header %q: status %d, called %v; want %d", tt.token, tt.header, rec.Code, called, tt.code)
+		}
+	}
+}
+
+func TestBanPlayer(t *testing.T) {
+	ctx := context.Background()
+	player := "ban-test-" + generateToken()
+
+	if rec := adminPost(banPlayerHandler, map[string]string{"reason": "spam"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("ban without a player: %d, want 400", rec.Code)
+	}
+	if rec := adminPost(banPlayerHandler, map[string]string{"playerId": player, "reason": "spam"}); rec.Code != http.StatusOK {
+		t.Fatalf("ban: %d %s", rec.Code, rec.Body)
+	}
+	if banned, err := playerBanned(ctx, player); err != nil || !banned {
+		t.Errorf("after ban: banned %v, %v", banned, err)
+	}
+
+	rec := httptest.NewRecorder()
+	playerBansHandler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(rec.Body.String(), player) {
+		t.Errorf("ban list %s doesn't include %s", rec.Body, player)
+	}
+
+	if rec := adminPost(unbanPlayerHandler, map[string]string{"playerId": player}); rec.Code != http.StatusNoContent {
+		t.Errorf("unban: %d", rec.Code)
+	}
+	if rec := adminPost(unbanPlayerHandler, map[string]string{"playerId": player}); rec.Code != http.StatusNotFound {
+		t.Errorf("unban again: %d, want 404", rec.Code)
+	}
+	if banned, _ := playerBanned(ctx, player); banned {
+		t.Error("still banned after unban")
+	}
+
+	actions := auditActions(t)
+	if len(actions) < 2 || actions[0] != "player.unban "+player || actions[1] != "player.ban "+player {
+		t.Errorf("audit log = %v, want the unban then the ban first", actions)
+	}
+}
+
+func TestDeleteEntryHandler(t *testing.T) {
+	saved := globalLeaderboard
+	globalLeaderboard = &Leaderboard{entries: []LeaderboardEntry{
+		{ID: "keep", PlayerID: "a", Score: 100},
+		{ID: "drop", PlayerID: "b", Score: 200},
+	}}
+	defer func() { globalLeaderboard = saved }()
+
+	if rec := adminPost(deleteEntryHandler, map[string]string{"id": "drop"}); rec.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+	}
+	if rec := adminPost(deleteEntryHandler, map[string]string{"id": "drop"}); rec.Code != http.StatusNotFound {
+		t.Errorf("delete again: %d, want 404", rec.Code)
+	}
+	if !equalIDs(globalLeaderboard.entries, []LeaderboardEntry{{ID: "keep"}}) {
+		t.Errorf("entries after delete = %v", ids(globalLeaderboard.entries))
+	}
+	if actions := auditActions(t); len(actions) == 0 || actions[0] != "entry.delete drop" {
+		t.Errorf("audit log = %v, want entry.delete first", actions)
+	}
+}
+
+func TestRefreshStatsHandler(t *testing.T) {
+	saved := globalLeaderboard
+	defer func() { globalLeaderboard = saved }()
+	globalLeaderboard = &Leaderboard{entries: []LeaderboardEntry{
+		{ID: "1", PlayerID: "a", Score: 100, Timestamp: time.Now()},
+		{ID: "2", PlayerID: "b", Score: 200, Timestamp: time.Now(), Mode: "fibonacci"},
+		{ID: "3", PlayerID: "c", Score: 300, Timestamp: time.Now()},
+	}}
+	for i := range globalLeaderboard.entries {
+		globalLeaderboard.entries[i].fillPartition()
+	}
+
+	rec := adminPost(refreshStatsHandler, nil)
+	var stats LeaderboardStats
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&stats) != nil {
+		t.Fatalf("refresh: %d %s", rec.Code, rec.Body)
+	}
+	if stats.TotalGames != 2 || stats.HighestScore != 300 {
+		t.Errorf("refreshed stats: %d games, highest %d; want the 2 classic games", stats.TotalGames, stats.HighestScore)
+	}
+}
+
+func TestEndSessionHandler(t *testing.T) {
+	game := newGameState(engine.Rules{})
+	game.PlayerID = "end-test-" + generateToken()
+	finished := newGameState(engine.Rules{})
+	finished.ID += "-over"
+	finished.GameOver = true
+	useFakeDynamoDB(t, []map[string]types.AttributeValue{sessionItem(t, game), sessionItem(t, finished)})
+
+	if rec := adminPost(endSessionHandler, map[string]string{"id": game.ID}); rec.Code != http.StatusOK {
+		t.Fatalf("end: %d %s", rec.Code, rec.Body)
+	}
+	rec := httptest.NewRecorder()
+	sessionHandler(rec, httptest.NewRequest(http.MethodGet, "/?id="+game.ID, nil))
+	var got GameState
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || !got.GameOver {
+		t.Errorf("session after end: over %v, %v", got.GameOver, err)
+	}
+
+	if rec := adminPost(endSessionHandler, map[string]string{"id": finished.ID}); rec.Code != http.StatusConflict {
+		t.Errorf("ending a finished game: %d, want 409", rec.Code)
+	}
+	if rec := adminPost(endSessionHandler, map[string]string{"id": "nope"}); rec.Code != http.StatusNotFound {
+		t.Errorf("ending an unknown game: %d, want 404", rec.Code)
+	}
+}
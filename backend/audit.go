@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// auditLogRetention is how long audit log days are kept (AUDIT_LOG_RETENTION)
+var auditLogRetention = durationFromEnv("AUDIT_LOG_RETENTION", 365*24*time.Hour)
+
+// AuditEvent records one admin action
+type AuditEvent struct {
+	At        time.Time      `json:"at"`
+	Action    string         `json:"action"` // such as "entry.delete"
+	Target    string         `json:"target,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"requestId,omitempty"`
+	Source    string         `json:"source,omitempty"` // the client's address
+}
+
+// auditKey is the record holding the audit log of a day (UTC). Admin actions
+// are rare enough for a day's events to fit in one record.
+func auditKey(date string) string {
+	return "audit#" + date
+}
+
+// audit writes an admin action to the audit log, in the sessions table and in
+// the server log. It is called once the action has succeeded; failing to
+// record it is logged but doesn't undo the action.
+func audit(r *http.Request, action, target string, details map[string]any) {
+	event := AuditEvent{
+		At:        time.Now().UTC(),
+		Action:    action,
+		Target:    target,
+		Details:   details,
+		RequestID: requestIDFromContext(r.Context()),
+		Source:    r.RemoteAddr,
+	}
+	// Behind the load balancer, the last forwarded address is the one it
+	// appended. Anything before it came from the client and can be forged.
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		event.Source = strings.TrimSpace(hops[len(hops)-1])
+	}
+
+	logger := loggerFromContext(r.Context())
+	logger.Info("admin action", "action", action, "target", target, "details", details, "source", event.Source)
+
+	// The action happened even if the client has gone away
+	ctx := context.WithoutCancel(r.Context())
+	if _, err := updateRecord(ctx, auditKey(event.At.Format(time.DateOnly)), auditLogRetention, func(events *[]AuditEvent, _ bool) error {
+		*events = append(*events, event)
+		return nil
+	}); err != nil {
+		logger.Error("failed to write audit log", "action", action, "target", target, "error", err)
+	}
+}
+
+// auditLogHandler lists a day's admin actions, newest first. The day is the
+// date query parameter (YYYY-MM-DD, UTC), today by default.
+func auditLogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = time.Now().UTC().Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, date); err != nil {
+		http.Error(w, "Invalid date: use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	var events []AuditEvent
+	if _, err := loadRecord(r.Context(), auditKey(date), &events); err != nil {
+		loggerFromContext(r.Context()).Error("failed to load audit log", "date", date, "error", err)
+		http.Error(w, "Failed to load audit log", http.StatusInternalServerError)
+		return
+	}
+	newestFirst := make([]AuditEvent, 0, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		newestFirst = append(newestFirst, events[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"date":   date,
+		"events": newestFirst,
+	})
+}
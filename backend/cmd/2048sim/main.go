@@ -0,0 +1,169 @@
+// Command 2048sim plays many headless games in parallel with a chosen
+// strategy and reports score, max tile, win rate and game length statistics.
+// It uses the same engine package as the server.
+//
+// Usage:
+//
+//	go run ./cmd/2048sim -games 10000 -strategy expectimax -depth 2 -format table
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"2048game/engine"
+)
+
+// GameResult is the outcome of one simulated game
+type GameResult struct {
+	Game    int   `json:"game"`
+	Seed    int64 `json:"seed"`
+	Score   int   `json:"score"`
+	MaxTile int   `json:"maxTile"`
+	Moves   int   `json:"moves"`
+	Won     bool  `json:"won"`
+}
+
+func main() {
+	games := flag.Int("games", 1000, "number of games to play")
+	strategyName := flag.String("strategy", "random", "strategy: random, greedy, corner or expectimax")
+	depth := flag.Int("depth", 2, "search depth for expectimax")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of games played in parallel")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "base seed; game i uses seed+i")
+	format := flag.String("format", "table", "output format: table, json or csv (one row per game)")
+	maxMoves := flag.Int("max-moves", 0, "stop each game after this many moves (0 = play until game over)")
+	spawn := flag.String("spawn", "", "spawn weights as value:weight pairs, e.g. 2:0.8,4:0.15,8:0.05 (default classic 2:0.9,4:0.1)")
+	tilesPerMove := flag.Int("tiles-per-move", 1, "tiles spawned after each move")
+	adversarial := flag.Bool("adversarial", false, "place each new tile where it hurts the player most")
+	variant := flag.String("variant", engine.VariantClassic, fmt.Sprintf("merge rule variant: %v", engine.Variants()))
+	flag.Parse()
+
+	weights, err := parseSpawnWeights(*spawn)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	rules := engine.Rules{Variant: *variant, SpawnWeights: weights, TilesPerMove: *tilesPerMove, Adversarial: *adversarial}
+	if err := rules.Validate(); err != nil {
+		fatalf("invalid rules: %v", err)
+	}
+	if *strategyName == "expectimax" && !rules.IsClassic() {
+		fatalf("expectimax only supports classic rules (no -variant, -spawn, -tiles-per-move or -adversarial)")
+	}
+
+	if _, err := newStrategy(*strategyName, *depth); err != nil {
+		fatalf("%v", err)
+	}
+	if *games < 1 || *workers < 1 {
+		fatalf("games and workers must be positive")
+	}
+	if *format != "table" && *format != "json" && *format != "csv" {
+		fatalf("unknown format %q (want table, json or csv)", *format)
+	}
+
+	start := time.Now()
+	results := simulate(*games, *workers, *seed, *maxMoves, rules, func() Strategy {
+		s, _ := newStrategy(*strategyName, *depth)
+		return s
+	})
+	elapsed := time.Since(start)
+
+	switch *format {
+	case "table":
+		err = writeTable(os.Stdout, summarize(*strategyName, rules, results, elapsed))
+	case "json":
+		err = writeJSON(os.Stdout, summarize(*strategyName, rules, results, elapsed))
+	case "csv":
+		err = writeCSV(os.Stdout, results)
+	}
+	if err != nil {
+		fatalf("writing report: %v", err)
+	}
+}
+
+// simulate plays games across workers goroutines. Each worker gets its own
+// strategy instance; game i is seeded with seed+i so runs are reproducible
+// regardless of scheduling.
+func simulate(games, workers int, seed int64, maxMoves int, rules engine.Rules, newStrategy func() Strategy) []GameResult {
+	results := make([]GameResult, games)
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			strategy := newStrategy()
+			for i := range next {
+				results[i] = playGame(i, seed+int64(i), maxMoves, rules, strategy)
+			}
+		}()
+	}
+
+	for i := 0; i < games; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
+
+// playGame plays a single game to completion
+func playGame(index int, seed int64, maxMoves int, rules engine.Rules, strategy Strategy) GameResult {
+	rng := rand.New(rand.NewSource(seed))
+	g := engine.NewWithRules(rng, rules)
+
+	moves := 0
+	for !g.GameOver && (maxMoves == 0 || moves < maxMoves) {
+		if !g.Move(strategy.Choose(g, rng)) {
+			// Strategies only pick legal moves; guard against a stuck loop anyway
+			break
+		}
+		moves++
+	}
+
+	return GameResult{
+		Game:    index,
+		Seed:    seed,
+		Score:   g.Score,
+		MaxTile: g.Board.MaxTile(),
+		Moves:   moves,
+		Won:     g.Won,
+	}
+}
+
+// parseSpawnWeights parses "2:0.9,4:0.1" into spawn weights; "" means classic
+func parseSpawnWeights(s string) ([]engine.SpawnWeight, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var weights []engine.SpawnWeight
+	for _, pair := range strings.Split(s, ",") {
+		value, weight, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("spawn weight %q must look like value:weight", pair)
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("spawn value %q: %v", value, err)
+		}
+		w, err := strconv.ParseFloat(weight, 64)
+		if err != nil {
+			return nil, fmt.Errorf("spawn weight %q: %v", weight, err)
+		}
+		weights = append(weights, engine.SpawnWeight{Value: v, Weight: w})
+	}
+	return weights, nil
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "2048sim: "+format+"\n", args...)
+	os.Exit(2)
+}
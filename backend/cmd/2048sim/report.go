@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"2048game/engine"
+)
+
+// Summary aggregates the results of a simulation run
+type Summary struct {
+	Strategy     string       `json:"strategy"`
+	Rules        string       `json:"rules"`
+	Games        int          `json:"games"`
+	ElapsedSec   float64      `json:"elapsedSeconds"`
+	GamesPerSec  float64      `json:"gamesPerSecond"`
+	WinRate      float64      `json:"winRate"`
+	MovesPerGame float64      `json:"movesPerGame"`
+	Score        Distribution `json:"score"`
+	MaxTiles     []TileCount  `json:"maxTiles"`
+}
+
+// Distribution describes a set of integer samples
+type Distribution struct {
+	Min    int     `json:"min"`
+	P10    int     `json:"p10"`
+	P25    int     `json:"p25"`
+	Median int     `json:"median"`
+	P75    int     `json:"p75"`
+	P90    int     `json:"p90"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+}
+
+// TileCount is how many games ended with a given max tile
+type TileCount struct {
+	Tile     int     `json:"tile"`
+	Games    int     `json:"games"`
+	Fraction float64 `json:"fraction"`
+	// AtLeast is the fraction of games that reached this tile or higher
+	AtLeast float64 `json:"atLeast"`
+}
+
+func summarize(strategy string, rules engine.Rules, results []GameResult, elapsed time.Duration) Summary {
+	n := len(results)
+	scores := make([]int, n)
+	tiles := make(map[int]int)
+	wins, moves := 0, 0
+	for i, r := range results {
+		scores[i] = r.Score
+		tiles[r.MaxTile]++
+		moves += r.Moves
+		if r.Won {
+			wins++
+		}
+	}
+
+	s := Summary{
+		Strategy:     strategy,
+		Rules:        rules.Name(),
+		Games:        n,
+		ElapsedSec:   elapsed.Seconds(),
+		GamesPerSec:  float64(n) / elapsed.Seconds(),
+		WinRate:      float64(wins) / float64(n),
+		MovesPerGame: float64(moves) / float64(n),
+		Score:        distribution(scores),
+	}
+
+	// Highest tile first, so AtLeast accumulates downwards
+	tileValues := make([]int, 0, len(tiles))
+	for t := range tiles {
+		tileValues = append(tileValues, t)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(tileValues)))
+	cumulative := 0
+	for _, t := range tileValues {
+		cumulative += tiles[t]
+		s.MaxTiles = append(s.MaxTiles, TileCount{
+			Tile:     t,
+			Games:    tiles[t],
+			Fraction: float64(tiles[t]) / float64(n),
+			AtLeast:  float64(cumulative) / float64(n),
+		})
+	}
+
+	return s
+}
+
+func distribution(samples []int) Distribution {
+	sorted := append([]int(nil), samples...)
+	sort.Ints(sorted)
+
+	total := 0
+	for _, v := range sorted {
+		total += v
+	}
+
+	pct := func(p float64) int {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	return Distribution{
+		Min:    sorted[0],
+		P10:    pct(0.10),
+		P25:    pct(0.25),
+		Median: pct(0.50),
+		P75:    pct(0.75),
+		P90:    pct(0.90),
+		Max:    sorted[len(sorted)-1],
+		Mean:   float64(total) / float64(len(sorted)),
+	}
+}
+
+func writeTable(w io.Writer, s Summary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Strategy\t%s\n", s.Strategy)
+	fmt.Fprintf(tw, "Rules\t%s\n", s.Rules)
+	fmt.Fprintf(tw, "Games\t%d (%.1fs, %.1f games/s)\n", s.Games, s.ElapsedSec, s.GamesPerSec)
+	fmt.Fprintf(tw, "Win rate\t%.2f%%\n", 100*s.WinRate)
+	fmt.Fprintf(tw, "Moves per game\t%.1f\n", s.MovesPerGame)
+	fmt.Fprintln(tw)
+
+	d := s.Score
+	fmt.Fprintln(tw, "Score\tmin\tp10\tp25\tmedian\tp75\tp90\tmax\tmean")
+	fmt.Fprintf(tw, "\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%.1f\n", d.Min, d.P10, d.P25, d.Median, d.P75, d.P90, d.Max, d.Mean)
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "Max tile\tgames\tshare\treached")
+	for _, t := range s.MaxTiles {
+		fmt.Fprintf(tw, "%d\t%d\t%.2f%%\t%.2f%%\n", t.Tile, t.Games, 100*t.Fraction, 100*t.AtLeast)
+	}
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, s Summary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// writeCSV writes one row per game, for analysis in a spreadsheet
+func writeCSV(w io.Writer, results []GameResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"game", "seed", "score", "maxTile", "moves", "won"})
+	for _, r := range results {
+		cw.Write([]string{
+			strconv.Itoa(r.Game),
+			strconv.FormatInt(r.Seed, 10),
+			strconv.Itoa(r.Score),
+			strconv.Itoa(r.MaxTile),
+			strconv.Itoa(r.Moves),
+			strconv.FormatBool(r.Won),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+
+	"2048game/engine"
+)
+
+// Strategy picks the next move for a game. Implementations are used by a
+// single goroutine at a time.
+type Strategy interface {
+	Choose(g *engine.Game, rng *rand.Rand) engine.Direction
+}
+
+var strategyNames = []string{"random", "greedy", "corner", "expectimax"}
+
+// newStrategy returns the strategy called name
+func newStrategy(name string, depth int) (Strategy, error) {
+	switch name {
+	case "random":
+		return randomStrategy{}, nil
+	case "greedy":
+		return greedyStrategy{}, nil
+	case "corner":
+		return cornerStrategy{}, nil
+	case "expectimax":
+		if depth < 1 {
+			return nil, fmt.Errorf("expectimax depth must be at least 1")
+		}
+		return &expectimaxStrategy{depth: depth}, nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q (want one of %v)", name, strategyNames)
+}
+
+// randomStrategy plays a uniformly random legal move
+type randomStrategy struct{}
+
+func (randomStrategy) Choose(g *engine.Game, rng *rand.Rand) engine.Direction {
+	legal := g.Legal()
+	return legal[rng.Intn(len(legal))]
+}
+
+// greedyStrategy plays the move that scores the most points right now,
+// preferring the one that leaves the most empty cells on a tie
+type greedyStrategy struct{}
+
+func (greedyStrategy) Choose(g *engine.Game, rng *rand.Rand) engine.Direction {
+	best, bestScore, bestEmpty := engine.Direction(""), -1, -1
+	for _, dir := range engine.Directions {
+		next, points, moved := g.Rules.Slide(g.Board, dir)
+		if !moved {
+			continue
+		}
+		empty := len(next.EmptyCells())
+		if points > bestScore || (points == bestScore && empty > bestEmpty) {
+			best, bestScore, bestEmpty = dir, points, empty
+		}
+	}
+	return best
+}
+
+// cornerStrategy keeps the largest tile in the bottom-left corner by always
+// preferring down, then left, then right, and only moving up when forced
+type cornerStrategy struct{}
+
+var cornerOrder = [...]engine.Direction{engine.Down, engine.Left, engine.Right, engine.Up}
+
+func (cornerStrategy) Choose(g *engine.Game, rng *rand.Rand) engine.Direction {
+	for _, dir := range cornerOrder {
+		if _, _, moved := g.Rules.Slide(g.Board, dir); moved {
+			return dir
+		}
+	}
+	return engine.Up
+}
+
+// expectimaxStrategy searches depth moves ahead, averaging over tile spawns
+// and scoring leaves with a row heuristic. It searches with the bitboard
+// engine and weighs spawns as classic does (a 2 nine times in ten, otherwise
+// a 4), so it only supports classic rules.
+type expectimaxStrategy struct {
+	depth int
+	// beyondBitboard is set once a game has a tile the bitboard can't hold
+	beyondBitboard bool
+}
+
+func (s *expectimaxStrategy) Choose(g *engine.Game, rng *rand.Rand) engine.Direction {
+	bb, ok := g.Board.ToBitboard()
+	if !ok {
+		// Only a tile above 32768 gets here under classic rules
+		if !s.beyondBitboard {
+			s.beyondBitboard = true
+			fmt.Fprintf(os.Stderr, "2048sim: expectimax can't search boards with a tile above 32768; playing greedy moves instead\n")
+		}
+		return greedyStrategy{}.Choose(g, rng)
+	}
+	best, bestValue := engine.Direction(""), math.Inf(-1)
+	for _, dir := range engine.Directions {
+		next, _, moved := bb.Move(dir)
+		if !moved {
+			continue
+		}
+		if v := s.chance(next, s.depth); v > bestValue {
+			best, bestValue = dir, v
+		}
+	}
+	return best
+}
+
+// chance averages over every possible spawn on bb
+func (s *expectimaxStrategy) chance(bb engine.Bitboard, depth int) float64 {
+	empty := 0
+	total := 0.0
+	for i := 0; i < engine.Size*engine.Size; i++ {
+		shift := uint(4 * i)
+		if (bb>>shift)&0xF != 0 {
+			continue
+		}
+		empty++
+		total += 0.9 * s.max(bb|1<<shift, depth-1)
+		total += 0.1 * s.max(bb|2<<shift, depth-1)
+	}
+	if empty == 0 {
+		return s.max(bb, depth-1)
+	}
+	return total / float64(empty)
+}
+
+// max returns the value of the best move from bb
+func (s *expectimaxStrategy) max(bb engine.Bitboard, depth int) float64 {
+	if depth == 0 {
+		return heuristic(bb)
+	}
+	best := math.Inf(-1)
+	for _, dir := range engine.Directions {
+		next, _, moved := bb.Move(dir)
+		if !moved {
+			continue
+		}
+		if v := s.chance(next, depth); v > best {
+			best = v
+		}
+	}
+	if math.IsInf(best, -1) {
+		// No moves left: heavily penalise losing
+		return heuristic(bb) - 1e6
+	}
+	return best
+}
+
+// rowHeuristic scores every possible 16-bit row: empty cells, merge
+// opportunities and monotonicity are rewarded, large unsorted tiles penalised
+var rowHeuristic [1 << 16]float64
+
+func init() {
+	for row := 0; row < 1<<16; row++ {
+		var cells [engine.Size]int
+		for c := range cells {
+			cells[c] = (row >> (4 * c)) & 0xF
+		}
+
+		sum, empty, merges := 0.0, 0, 0
+		prev, counter := 0, 0
+		for _, e := range cells {
+			sum += math.Pow(float64(e), 3.5)
+			if e == 0 {
+				empty++
+				continue
+			}
+			if prev == e {
+				counter++
+			} else if counter > 0 {
+				merges += 1 + counter
+				counter = 0
+			}
+			prev = e
+		}
+		if counter > 0 {
+			merges += 1 + counter
+		}
+
+		monoLeft, monoRight := 0.0, 0.0
+		for c := 1; c < engine.Size; c++ {
+			a, b := float64(cells[c-1]), float64(cells[c])
+			if a > b {
+				monoLeft += math.Pow(a, 4) - math.Pow(b, 4)
+			} else {
+				monoRight += math.Pow(b, 4) - math.Pow(a, 4)
+			}
+		}
+
+		rowHeuristic[row] = 200000 +
+			270*float64(empty) +
+			700*float64(merges) -
+			47*math.Min(monoLeft, monoRight) -
+			11*sum
+	}
+}
+
+func heuristic(bb engine.Bitboard) float64 {
+	t := bb.Transpose()
+	v := 0.0
+	for r := 0; r < engine.Size; r++ {
+		v += rowHeuristic[uint16(bb>>(16*r))]
+		v += rowHeuristic[uint16(t>>(16*r))]
+	}
+	return v
+}
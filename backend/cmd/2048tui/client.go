@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"2048game/engine"
+)
+
+// gameView is what the client needs to draw a game
+type gameView struct {
+	ID       string       `json:"id"`
+	Board    engine.Board `json:"board"`
+	Score    int          `json:"score"`
+	GameOver bool         `json:"gameOver"`
+	Won      bool         `json:"won"`
+}
+
+// gameClient plays games either against the backend API or locally
+type gameClient interface {
+	NewGame() (*gameView, error)
+	Move(dir engine.Direction) (*gameView, error)
+	State() (*gameView, error)
+	// Online reports whether scores can be submitted to the leaderboard
+	Online() bool
+}
+
+// apiClient plays through the /game endpoints
+type apiClient struct {
+	baseURL string
+	http    *http.Client
+	gameID  string
+}
+
+func newAPIClient(baseURL string) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *apiClient) Online() bool { return true }
+
+func (c *apiClient) NewGame() (*gameView, error) {
+	var view gameView
+	if err := c.do(http.MethodPost, "/game/new", nil, &view); err != nil {
+		return nil, err
+	}
+	c.gameID = view.ID
+	return &view, nil
+}
+
+func (c *apiClient) Move(dir engine.Direction) (*gameView, error) {
+	var view gameView
+	body := map[string]string{"id": c.gameID, "direction": string(dir)}
+	if err := c.do(http.MethodPost, "/game/move", body, &view); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+func (c *apiClient) State() (*gameView, error) {
+	var view gameView
+	if err := c.do(http.MethodGet, "/game/state?id="+c.gameID, nil, &view); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// scoreSubmission matches the body accepted by /leaderboard/submit
+type scoreSubmission struct {
+	GameID   string `json:"gameId"`
+	PlayerID string `json:"playerId"`
+	Name     string `json:"name"`
+	Score    int    `json:"score"`
+	Duration int    `json:"duration"`
+	Moves    int    `json:"moves"`
+}
+
+// SubmitScore submits the score of the current game, which the server checks
+// against its own copy
+func (c *apiClient) SubmitScore(s scoreSubmission) error {
+	s.GameID = c.gameID
+	return c.do(http.MethodPost, "/leaderboard/submit", s, nil)
+}
+
+// do sends a JSON request and decodes the JSON response into out (if non-nil)
+func (c *apiClient) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// localClient plays with the engine in-process, without a server
+type localClient struct {
+	rng  *rand.Rand
+	game *engine.Game
+}
+
+func newLocalClient(seed int64) *localClient {
+	return &localClient{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (c *localClient) Online() bool { return false }
+
+func (c *localClient) NewGame() (*gameView, error) {
+	c.game = engine.New(c.rng)
+	return c.State()
+}
+
+func (c *localClient) Move(dir engine.Direction) (*gameView, error) {
+	c.game.Move(dir)
+	return c.State()
+}
+
+func (c *localClient) State() (*gameView, error) {
+	return &gameView{
+		ID:       "offline",
+		Board:    c.game.Board,
+		Score:    c.game.Score,
+		GameOver: c.game.GameOver,
+		Won:      c.game.Won,
+	}, nil
+}
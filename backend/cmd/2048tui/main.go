@@ -0,0 +1,272 @@
+// Command 2048tui is a terminal client for the 2048 backend. It renders the
+// board in colour, reads arrow keys or WASD, and can submit the final score
+// to the leaderboard. With -offline it plays locally using the engine package.
+//
+// Usage:
+//
+//	go run ./cmd/2048tui -server http://localhost:8000 -name Alice
+//	go run ./cmd/2048tui -offline
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"2048game/engine"
+)
+
+func main() {
+	server := flag.String("server", "http://localhost:8000", "backend base URL")
+	offline := flag.Bool("offline", false, "play locally without a server")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed for offline games")
+	name := flag.String("name", "", "name to submit to the leaderboard (prompted if empty)")
+	playerID := flag.String("player-id", "", "leaderboard player ID (default: generated and saved in the user config directory)")
+	flag.Parse()
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		fatalf("stdin is not a terminal")
+	}
+
+	var client gameClient
+	if *offline {
+		client = newLocalClient(*seed)
+	} else {
+		client = newAPIClient(*server)
+	}
+
+	p := &player{
+		client:   client,
+		fd:       fd,
+		name:     *name,
+		playerID: *playerID,
+	}
+	if err := p.run(); err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// player runs the interactive loop for one terminal
+type player struct {
+	client   gameClient
+	fd       int
+	oldState *term.State
+
+	view      *gameView
+	moves     int
+	startedAt time.Time
+	submitted bool
+	status    string
+
+	name     string
+	playerID string
+}
+
+func (p *player) run() error {
+	if err := p.newGame(); err != nil {
+		return err
+	}
+
+	if err := p.enterRaw(); err != nil {
+		return err
+	}
+	defer p.exitRaw()
+
+	reader := bufio.NewReader(os.Stdin)
+	buf := make([]byte, 8)
+	for {
+		p.draw()
+
+		n, err := reader.Read(buf)
+		if err != nil {
+			return err
+		}
+		key := buf[:n]
+
+		switch {
+		case len(key) == 1 && (key[0] == 'q' || key[0] == 'Q' || key[0] == 3): // 3 is Ctrl-C
+			fmt.Print("\r\n")
+			return nil
+		case len(key) == 1 && (key[0] == 'n' || key[0] == 'N'):
+			if err := p.newGame(); err != nil {
+				p.status = "Error: " + err.Error()
+			}
+		default:
+			if dir, ok := parseDirection(key); ok {
+				p.move(dir)
+			}
+		}
+	}
+}
+
+// parseDirection maps arrow key escape sequences and WASD to directions
+func parseDirection(key []byte) (engine.Direction, bool) {
+	if len(key) >= 3 && key[0] == 0x1b && key[1] == '[' {
+		switch key[2] {
+		case 'A':
+			return engine.Up, true
+		case 'B':
+			return engine.Down, true
+		case 'C':
+			return engine.Right, true
+		case 'D':
+			return engine.Left, true
+		}
+		return "", false
+	}
+	if len(key) != 1 {
+		return "", false
+	}
+	switch key[0] {
+	case 'w', 'W':
+		return engine.Up, true
+	case 's', 'S':
+		return engine.Down, true
+	case 'd', 'D':
+		return engine.Right, true
+	case 'a', 'A':
+		return engine.Left, true
+	}
+	return "", false
+}
+
+func (p *player) newGame() error {
+	view, err := p.client.NewGame()
+	if err != nil {
+		return err
+	}
+	p.view = view
+	p.moves = 0
+	p.startedAt = time.Now()
+	p.submitted = false
+	p.status = ""
+	return nil
+}
+
+func (p *player) move(dir engine.Direction) {
+	if p.view.GameOver {
+		return
+	}
+
+	before := p.view.Board
+	view, err := p.client.Move(dir)
+	if err != nil {
+		p.status = "Error: " + err.Error()
+		return
+	}
+	p.view = view
+	p.status = ""
+	if view.Board != before {
+		p.moves++
+	}
+
+	if view.GameOver && p.client.Online() && !p.submitted {
+		p.draw()
+		p.offerSubmit()
+	}
+}
+
+func (p *player) draw() {
+	fmt.Print(render(p.view, p.status, p.client.Online(), p.moves))
+}
+
+// offerSubmit asks whether to submit the final score and does so. The
+// terminal is switched back to line mode while prompting.
+func (p *player) offerSubmit() {
+	api, ok := p.client.(*apiClient)
+	if !ok || p.view.Score == 0 {
+		return
+	}
+
+	p.exitRaw()
+	defer p.enterRaw()
+
+	in := bufio.NewReader(os.Stdin)
+	fmt.Print("Submit score to the leaderboard? [y/N] ")
+	answer, _ := in.ReadString('\n')
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		p.status = "Score not submitted."
+		return
+	}
+
+	name := p.name
+	for name == "" {
+		fmt.Print("Name: ")
+		line, err := in.ReadString('\n')
+		if err != nil {
+			p.status = "Score not submitted."
+			return
+		}
+		name = strings.TrimSpace(line)
+	}
+	p.name = name
+
+	err := api.SubmitScore(scoreSubmission{
+		PlayerID: p.ensurePlayerID(),
+		Name:     name,
+		Score:    p.view.Score,
+		Duration: int(time.Since(p.startedAt).Seconds()),
+		Moves:    p.moves,
+	})
+	if err != nil {
+		p.status = "Error submitting score: " + err.Error()
+		return
+	}
+	p.submitted = true
+	p.status = fmt.Sprintf("Submitted %d points as %s.", p.view.Score, name)
+}
+
+// ensurePlayerID returns the player ID, loading or creating the one saved in
+// the user config directory so rankings follow the same player across runs
+func (p *player) ensurePlayerID() string {
+	if p.playerID != "" {
+		return p.playerID
+	}
+
+	id := fmt.Sprintf("player_%d_tui", time.Now().UnixNano())
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		p.playerID = id
+		return id
+	}
+
+	path := filepath.Join(dir, "2048tui", "player-id")
+	if data, err := os.ReadFile(path); err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		p.playerID = strings.TrimSpace(string(data))
+		return p.playerID
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+		os.WriteFile(path, []byte(id+"\n"), 0o644)
+	}
+	p.playerID = id
+	return id
+}
+
+func (p *player) enterRaw() error {
+	state, err := term.MakeRaw(p.fd)
+	if err != nil {
+		return err
+	}
+	p.oldState = state
+	return nil
+}
+
+func (p *player) exitRaw() {
+	if p.oldState != nil {
+		term.Restore(p.fd, p.oldState)
+		p.oldState = nil
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "2048tui: "+format+"\n", args...)
+	os.Exit(1)
+}
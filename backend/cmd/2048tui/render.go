@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"2048game/engine"
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiClear = "\x1b[H\x1b[2J"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	cellWidth = 7
+)
+
+// tileColors maps tile values to 256-colour background/foreground pairs,
+// roughly following the web frontend's palette
+var tileColors = map[int][2]int{
+	0:    {250, 245},
+	2:    {255, 240},
+	4:    {230, 240},
+	8:    {215, 255},
+	16:   {209, 255},
+	32:   {203, 255},
+	64:   {196, 255},
+	128:  {222, 255},
+	256:  {221, 255},
+	512:  {220, 255},
+	1024: {214, 255},
+	2048: {178, 255},
+}
+
+// obstacleColors is used for blockers and rocks
+var obstacleColors = [2]int{238, 250}
+
+func tileStyle(t engine.Tile) string {
+	colors, ok := tileColors[int(t)]
+	if t.IsObstacle() {
+		colors, ok = obstacleColors, true
+	}
+	if !ok {
+		colors = [2]int{235, 255}
+	}
+	return fmt.Sprintf("\x1b[48;5;%dm\x1b[38;5;%dm", colors[0], colors[1])
+}
+
+// render draws the whole screen. Lines end in \r\n because the terminal is
+// in raw mode.
+func render(view *gameView, status string, online bool, moves int) string {
+	var b strings.Builder
+	b.WriteString(ansiClear)
+
+	mode := "online"
+	if !online {
+		mode = "offline"
+	}
+	fmt.Fprintf(&b, "%s2048%s  %s  score %s%d%s  moves %d\r\n\r\n",
+		ansiBold, ansiReset, mode, ansiBold, view.Score, ansiReset, moves)
+
+	for r := 0; r < engine.Size; r++ {
+		// Each tile is three terminal rows tall, with the value in the middle
+		for line := 0; line < 3; line++ {
+			b.WriteString("  ")
+			for c := 0; c < engine.Size; c++ {
+				t := view.Board[r][c]
+				text := ""
+				if line == 1 {
+					text = tileText(t)
+				}
+				b.WriteString(tileStyle(t))
+				b.WriteString(center(text, cellWidth))
+				b.WriteString(ansiReset)
+				b.WriteString(" ")
+			}
+			b.WriteString("\r\n")
+		}
+		b.WriteString("\r\n")
+	}
+
+	switch {
+	case view.GameOver:
+		b.WriteString(ansiBold + "Game over!" + ansiReset + "\r\n")
+	case view.Won:
+		b.WriteString(ansiBold + "You reached 2048!" + ansiReset + " Keep going.\r\n")
+	default:
+		b.WriteString("\r\n")
+	}
+	if status != "" {
+		b.WriteString(status + "\r\n")
+	}
+	b.WriteString(ansiDim + "arrows/WASD move · n new game · q quit" + ansiReset + "\r\n")
+	return b.String()
+}
+
+// tileText is the label for t: its value, "###" for a blocker or the hits
+// left on a rock
+func tileText(t engine.Tile) string {
+	switch {
+	case t.IsBlocker():
+		return "###"
+	case t.IsRock():
+		return "#" + strconv.Itoa(t.RockHits()) + "#"
+	case t == 0:
+		return ""
+	}
+	return strconv.Itoa(int(t))
+}
+
+func center(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	left := (width - len(s)) / 2
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", width-len(s)-left)
+}
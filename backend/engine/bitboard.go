@@ -0,0 +1,233 @@
+package engine
+
+// Bitboard is a 4x4 board packed into 64 bits for fast simulation. Each cell
+// is a 4-bit exponent (0 empty, 1 for 2, 2 for 4, ... 15 for 32768) and cell
+// (r, c) lives at nibble 4*r+c, so each row is 16 bits. Moves use precomputed
+// per-row lookup tables and give the same results as Slide.
+//
+// The one difference is at the top: two 32768 tiles don't merge, because
+// 65536 doesn't fit in a nibble. Slide merges them, so a Bitboard move that
+// leaves them side by side can differ from Slide, and ToBitboard refuses a
+// board holding 65536. Games don't get that far in practice.
+type Bitboard uint64
+
+// maxExponent is the largest exponent a nibble can hold
+const maxExponent = 15
+
+// Row lookup tables indexed by a 16-bit row. rowLeft/rowRight hold the row
+// after sliding it towards column 0/3, and rowLeftScore/rowRightScore the
+// points scored by its merges.
+var (
+	rowLeft       [1 << 16]uint16
+	rowRight      [1 << 16]uint16
+	rowLeftScore  [1 << 16]uint32
+	rowRightScore [1 << 16]uint32
+)
+
+func init() {
+	for row := 0; row < 1<<16; row++ {
+		cells := [Size]int{}
+		for c := 0; c < Size; c++ {
+			cells[c] = (row >> (4 * c)) & 0xF
+		}
+
+		left, score := slideRowLeft(cells)
+		rowLeft[row] = packRow(left)
+		rowLeftScore[row] = uint32(score)
+
+		reversed := [Size]int{cells[3], cells[2], cells[1], cells[0]}
+		right, score := slideRowLeft(reversed)
+		rowRight[row] = packRow([Size]int{right[3], right[2], right[1], right[0]})
+		rowRightScore[row] = uint32(score)
+	}
+}
+
+// slideRowLeft applies slideLeft's merge rule to a row of exponents
+func slideRowLeft(cells [Size]int) ([Size]int, int) {
+	temp := make([]int, 0, Size)
+	for _, e := range cells {
+		if e != 0 {
+			temp = append(temp, e)
+		}
+	}
+	score := 0
+	for j := 0; j < len(temp)-1; j++ {
+		if temp[j] == temp[j+1] && temp[j] < maxExponent {
+			temp[j]++
+			score += 1 << temp[j]
+			temp = append(temp[:j+1], temp[j+2:]...)
+		}
+	}
+	var out [Size]int
+	copy(out[:], temp)
+	return out, score
+}
+
+func packRow(cells [Size]int) uint16 {
+	var row uint16
+	for c := 0; c < Size; c++ {
+		row |= uint16(cells[c]) << (4 * c)
+	}
+	return row
+}
+
+// exponent returns log2(v) for a power of two tile, or 0 for an empty cell
+func exponent(v int) int {
+	e := 0
+	for v > 1 {
+		v >>= 1
+		e++
+	}
+	return e
+}
+
+// ToBitboard packs b. It returns false if a tile isn't a power of two between
+// 2 and 32768, including when b holds obstacles.
+func (b *Board) ToBitboard() (Bitboard, bool) {
+	var bb Bitboard
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			v := int(b[r][c])
+			if v == 0 {
+				continue
+			}
+			e := exponent(v)
+			if v < 2 || 1<<e != v || e > maxExponent {
+				return 0, false
+			}
+			bb |= Bitboard(e) << (4 * (Size*r + c))
+		}
+	}
+	return bb, true
+}
+
+// Board unpacks bb
+func (bb Bitboard) Board() Board {
+	var b Board
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			if e := bb.cell(r, c); e != 0 {
+				b[r][c] = 1 << e
+			}
+		}
+	}
+	return b
+}
+
+func (bb Bitboard) cell(r, c int) int {
+	return int(bb>>(4*(Size*r+c))) & 0xF
+}
+
+func (bb Bitboard) row(r int) uint16 {
+	return uint16(bb >> (16 * r))
+}
+
+// Transpose swaps rows and columns, so column moves (and column-wise
+// heuristics) can use row lookup tables
+func (bb Bitboard) Transpose() Bitboard {
+	x := uint64(bb)
+	a1 := x & 0xF0F00F0FF0F00F0F
+	a2 := x & 0x0000F0F00000F0F0
+	a3 := x & 0x0F0F00000F0F0000
+	a := a1 | (a2 << 12) | (a3 >> 12)
+	b1 := a & 0xFF00FF0000FF00FF
+	b2 := a & 0x00FF00FF00000000
+	b3 := a & 0x00000000FF00FF00
+	return Bitboard(b1 | (b2 >> 24) | (b3 << 24))
+}
+
+// Move slides every tile in dir, like Slide. It returns the new board, the
+// points scored and whether anything moved.
+func (bb Bitboard) Move(dir Direction) (Bitboard, int, bool) {
+	var table *[1 << 16]uint16
+	var scores *[1 << 16]uint32
+	switch dir {
+	case Left, Up:
+		table, scores = &rowLeft, &rowLeftScore
+	case Right, Down:
+		table, scores = &rowRight, &rowRightScore
+	default:
+		return bb, 0, false
+	}
+
+	src := bb
+	if dir == Up || dir == Down {
+		src = bb.Transpose()
+	}
+
+	var out Bitboard
+	score := 0
+	for r := 0; r < Size; r++ {
+		row := src.row(r)
+		out |= Bitboard(table[row]) << (16 * r)
+		score += int(scores[row])
+	}
+
+	if dir == Up || dir == Down {
+		out = out.Transpose()
+	}
+	return out, score, out != bb
+}
+
+// EmptyCount returns the number of empty cells
+func (bb Bitboard) EmptyCount() int {
+	n := 0
+	for i := 0; i < Size*Size; i++ {
+		if (bb>>(4*i))&0xF == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// MaxTile returns the largest tile value
+func (bb Bitboard) MaxTile() int {
+	max := 0
+	for i := 0; i < Size*Size; i++ {
+		if e := int(bb>>(4*i)) & 0xF; e > max {
+			max = e
+		}
+	}
+	if max == 0 {
+		return 0
+	}
+	return 1 << max
+}
+
+// CanMove reports whether any move is possible
+func (bb Bitboard) CanMove() bool {
+	for _, dir := range Directions {
+		if _, _, moved := bb.Move(dir); moved {
+			return true
+		}
+	}
+	return false
+}
+
+// SpawnTile places a 2 (90%) or a 4 (10%) on a random empty cell. It draws
+// from rng exactly like Game.SpawnTile with classic rules, so both engines
+// stay in lockstep when given identically seeded sources.
+func (bb Bitboard) SpawnTile(rng Source) Bitboard {
+	empty := bb.EmptyCount()
+	if empty == 0 {
+		return bb
+	}
+	if rng == nil {
+		rng = globalSource{}
+	}
+	target := rng.Intn(empty)
+	e := Bitboard(1)
+	if rng.Float64() < 0.1 {
+		e = 2
+	}
+	for i := 0; i < Size*Size; i++ {
+		if (bb>>(4*i))&0xF != 0 {
+			continue
+		}
+		if target == 0 {
+			return bb | e<<(4*i)
+		}
+		target--
+	}
+	return bb
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomExponentBoard returns a board with random tiles from 2 to 16384, which
+// both engines can represent and merge
+func randomExponentBoard(rng *rand.Rand) Board {
+	var b Board
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			if rng.Intn(3) > 0 {
+				b[r][c] = Tile(1 << (1 + rng.Intn(maxExponent-1)))
+			}
+		}
+	}
+	return b
+}
+
+// TestBitboardMatchesGame plays the same games with Game and Bitboard, with
+// identically seeded sources, comparing boards and scores after every move
+func TestBitboardMatchesGame(t *testing.T) {
+	moves := 2_000_000
+	if testing.Short() {
+		moves = 100_000
+	}
+
+	dirs := rand.New(rand.NewSource(2048))
+	played, games := 0, 0
+	for played < moves {
+		seed := int64(games)
+		games++
+
+		game := New(rand.New(rand.NewSource(seed)))
+		src := rand.New(rand.NewSource(seed))
+		bb, score := Bitboard(0).SpawnTile(src).SpawnTile(src), 0
+		if bb.Board() != game.Board {
+			t.Fatalf("game %d starts on %v with Game but %v with Bitboard", seed, game.Board, bb.Board())
+		}
+
+		for !game.GameOver && played < moves {
+			dir := Directions[dirs.Intn(len(Directions))]
+			before := game.Board
+
+			moved := game.Move(dir)
+			next, gained, bbMoved := bb.Move(dir)
+			if bbMoved {
+				next = next.SpawnTile(src)
+				score += gained
+			}
+			bb = next
+			played++
+
+			if moved != bbMoved || game.Score != score || game.Board != bb.Board() {
+				t.Fatalf("game %d, %s from %v: Game moved %v to %v scoring %d; Bitboard moved %v to %v scoring %d",
+					seed, dir, before, moved, game.Board, game.Score, bbMoved, bb.Board(), score)
+			}
+			if game.GameOver == bb.CanMove() {
+				t.Fatalf("game %d: GameOver is %v but Bitboard.CanMove is %v on %v", seed, game.GameOver, bb.CanMove(), game.Board)
+			}
+		}
+	}
+	t.Logf("%d moves over %d games", played, games)
+}
+
+// mustBitboard packs b, failing the test if it can't be packed
+func mustBitboard(t *testing.T, b Board) Bitboard {
+	t.Helper()
+	bb, ok := b.ToBitboard()
+	if !ok {
+		t.Fatalf("ToBitboard(%v) failed", b)
+	}
+	return bb
+}
+
+// TestBitboardMatchesSlideOnRandomBoards compares single moves on random
+// boards with high tiles, which random games rarely reach
+func TestBitboardMatchesSlideOnRandomBoards(t *testing.T) {
+	rng := rand.New(rand.NewSource(32768))
+	for i := 0; i < 200_000; i++ {
+		b := randomExponentBoard(rng)
+		bb := mustBitboard(t, b)
+		if bb.Board() != b {
+			t.Fatalf("round trip of %v gave %v", b, bb.Board())
+		}
+		for _, dir := range Directions {
+			want, wantScore, wantMoved := Slide(b, dir)
+			got, score, moved := bb.Move(dir)
+			if got.Board() != want || score != wantScore || moved != wantMoved {
+				t.Fatalf("%s on %v: Bitboard gave %v, %d, %v; Slide gave %v, %d, %v",
+					dir, b, got.Board(), score, moved, want, wantScore, wantMoved)
+			}
+		}
+	}
+}
+
+// TestBitboard32768 covers where the engines differ: Slide merges two 32768
+// tiles into 65536, which a nibble can't hold, so Bitboard leaves them be
+func TestBitboard32768(t *testing.T) {
+	b := Board{{32768, 32768, 0, 0}}
+	bb := mustBitboard(t, b)
+
+	slid, score, moved := Slide(b, Left)
+	if want := (Board{{65536}}); slid != want || score != 65536 || !moved {
+		t.Errorf("Slide = %v, %d, %v; want %v, 65536, true", slid, score, moved, want)
+	}
+
+	next, score, moved := bb.Move(Left)
+	if next != bb || score != 0 || moved {
+		t.Errorf("Bitboard.Move = %v, %d, %v; want the board unchanged", next.Board(), score, moved)
+	}
+	if _, ok := slid.ToBitboard(); ok {
+		t.Error("ToBitboard accepted a 65536 tile")
+	}
+}
+
+// benchBoards are random boards for the move benchmarks
+var benchBoards = func() []Board {
+	rng := rand.New(rand.NewSource(1))
+	boards := make([]Board, 1024)
+	for i := range boards {
+		boards[i] = randomExponentBoard(rng)
+	}
+	return boards
+}()
+
+var benchSink int
+
+func BenchmarkMove(b *testing.B) {
+	b.Run("bitboard", func(b *testing.B) {
+		bbs := make([]Bitboard, len(benchBoards))
+		for i, board := range benchBoards {
+			bbs[i], _ = board.ToBitboard()
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			next, score, _ := bbs[i%len(bbs)].Move(Directions[i%len(Directions)])
+			benchSink += int(next) + score
+		}
+	})
+	b.Run("slice", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			next, score, _ := Slide(benchBoards[i%len(benchBoards)], Directions[i%len(Directions)])
+			benchSink += int(next[0][0]) + score
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package engine
+
+// EmptyCells returns the [row, col] coordinates of every empty cell
+func (b *Board) EmptyCells() [][2]int {
+	empty := [][2]int{}
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			if b[r][c] == 0 {
+				empty = append(empty, [2]int{r, c})
+			}
+		}
+	}
+	return empty
+}
+
+// MaxTile returns the largest tile on the board
+func (b *Board) MaxTile() int {
+	max := 0
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			if v := int(b[r][c]); v > max {
+				max = v
+			}
+		}
+	}
+	return max
+}
+
+// HasObstacles reports whether any cell holds a blocker or a rock
+func (b *Board) HasObstacles() bool {
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			if b[r][c].IsObstacle() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CanMove reports whether any move is possible with the classic rules: some
+// tile has an empty or equal neighbour. Obstacles never move, so an empty
+// cell walled in by them doesn't count.
+func (b *Board) CanMove() bool {
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			t := b[r][c]
+			if t == 0 || t.IsObstacle() {
+				continue
+			}
+			if r > 0 && b[r-1][c] == 0 || r < Size-1 && (b[r+1][c] == 0 || b[r+1][c] == t) {
+				return true
+			}
+			if c > 0 && b[r][c-1] == 0 || c < Size-1 && (b[r][c+1] == 0 || b[r][c+1] == t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Slide moves every tile in dir with the classic rules, merging equal
+// neighbours once per move. It returns the new board, the points scored by
+// merges and whether anything moved. Use Rules.Slide for other variants.
+func Slide(b Board, dir Direction) (Board, int, bool) {
+	return slide(b, dir, classicMerge{})
+}
+
+func slide(b Board, dir Direction, merge MergeRule) (Board, int, bool) {
+	return slideTracked(b, dir, merge, nil)
+}
+
+// slideTracked is slide that also marks the cells holding newly merged tiles
+// in merged, if it isn't nil
+func slideTracked(b Board, dir Direction, merge MergeRule, merged *Board) (Board, int, bool) {
+	turn(&b, dir)
+	gained, moved := slideLeft(&b, merge, merged)
+	unturn(&b, dir)
+	if merged != nil {
+		unturn(merged, dir)
+	}
+	return b, gained, moved
+}
+
+// turn rotates b so that sliding dir becomes sliding left
+func turn(b *Board, dir Direction) {
+	switch dir {
+	case Up:
+		rotateLeft(b)
+	case Down:
+		rotateRight(b)
+	case Right:
+		rotate180(b)
+	}
+}
+
+// unturn undoes turn
+func unturn(b *Board, dir Direction) {
+	switch dir {
+	case Up:
+		rotateRight(b)
+	case Down:
+		rotateLeft(b)
+	case Right:
+		rotate180(b)
+	}
+}
+
+// slideLeft moves every row of b to the left, merging tiles with merge.
+// Obstacles split a row into segments that slide independently, and every
+// merge chips away at the rocks next to it. Merged cells are set to 1 in
+// mask if it isn't nil.
+func slideLeft(b *Board, merge MergeRule, mask *Board) (int, bool) {
+	gained := 0
+	moved := false
+	var merged [Size][Size]bool
+	anyMerged := false
+
+	for i := 0; i < Size; i++ {
+		for start := 0; start < Size; {
+			if b[i][start].IsObstacle() {
+				start++
+				continue
+			}
+			end := start
+			temp := make([]int, 0, Size)
+			for ; end < Size && !b[i][end].IsObstacle(); end++ {
+				if b[i][end] != 0 {
+					temp = append(temp, int(b[i][end]))
+				}
+			}
+
+			temp, bits, score := merge.Merge(temp)
+			gained += score
+			for j := start; j < end; j++ {
+				v := Tile(0)
+				if k := j - start; k < len(temp) {
+					v = Tile(temp[k])
+					if bits&(1<<k) != 0 {
+						merged[i][j] = true
+						anyMerged = true
+						if mask != nil {
+							mask[i][j] = 1
+						}
+					}
+				}
+				if b[i][j] != v {
+					moved = true
+				}
+				b[i][j] = v
+			}
+			start = end
+		}
+	}
+
+	if anyMerged {
+		hitRocks(b, &merged)
+	}
+	return gained, moved
+}
+
+// hitRocks takes one hit off every rock for each merged tile next to it
+func hitRocks(b *Board, merged *[Size][Size]bool) {
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			if !merged[r][c] {
+				continue
+			}
+			for _, d := range [...][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+				nr, nc := r+d[0], c+d[1]
+				if nr >= 0 && nr < Size && nc >= 0 && nc < Size && b[nr][nc].IsRock() {
+					b[nr][nc] = b[nr][nc].hit()
+				}
+			}
+		}
+	}
+}
+
+func rotateRight(b *Board) {
+	temp := Board{}
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			temp[c][Size-1-r] = b[r][c]
+		}
+	}
+	*b = temp
+}
+
+func rotateLeft(b *Board) {
+	temp := Board{}
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			temp[Size-1-c][r] = b[r][c]
+		}
+	}
+	*b = temp
+}
+
+func rotate180(b *Board) {
+	rotateRight(b)
+	rotateRight(b)
+}
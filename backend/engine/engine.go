@@ -0,0 +1,239 @@
+// Package engine implements the rules of 2048: sliding and merging tiles,
+// spawning new tiles and detecting wins and game over. It has no I/O and can
+// be used by the HTTP server, bots and command line tools alike.
+package engine
+
+import "math/rand"
+
+// Size is the width and height of the board
+const Size = 4
+
+// WinningTile is the tile value that wins a classic game
+const WinningTile = 2048
+
+// Board holds the tile in each cell; see Tile for empty cells and obstacles
+type Board [Size][Size]Tile
+
+// Direction is a move direction
+type Direction string
+
+const (
+	Up    Direction = "up"
+	Down  Direction = "down"
+	Left  Direction = "left"
+	Right Direction = "right"
+)
+
+// Directions lists every direction in a fixed order
+var Directions = [...]Direction{Up, Down, Left, Right}
+
+// Valid reports whether d is one of the four move directions
+func (d Direction) Valid() bool {
+	switch d {
+	case Up, Down, Left, Right:
+		return true
+	}
+	return false
+}
+
+// Source is the randomness used to spawn tiles. *rand.Rand satisfies it, so
+// a seeded source gives reproducible games.
+type Source interface {
+	Intn(n int) int
+	Float64() float64
+}
+
+// globalSource uses the math/rand top-level functions
+type globalSource struct{}
+
+func (globalSource) Intn(n int) int   { return rand.Intn(n) }
+func (globalSource) Float64() float64 { return rand.Float64() }
+
+// Game is the state of a single game
+type Game struct {
+	Board    Board `json:"board"`
+	Score    int   `json:"score"`
+	GameOver bool  `json:"gameOver"`
+	Won      bool  `json:"won"`
+	Rules    Rules `json:"rules"`
+
+	// Scripted games spawn tiles from Script instead of at random, and stop
+	// spawning once it runs out. ScriptPos is the next entry to use.
+	Scripted  bool    `json:"scripted,omitempty"`
+	Script    []Spawn `json:"script,omitempty"`
+	ScriptPos int     `json:"scriptPos,omitempty"`
+
+	rng Source
+}
+
+// Spawn is a scripted tile. If its cell is taken, the tile goes to the first
+// empty cell in reading order instead.
+type Spawn struct {
+	Value int `json:"value"`
+	Row   int `json:"row"`
+	Col   int `json:"col"`
+}
+
+// New starts a classic game with two random tiles. A nil rng uses math/rand.
+func New(rng Source) *Game {
+	return NewWithRules(rng, Rules{})
+}
+
+// NewWithRules starts a game with two tiles spawned according to rules.
+// Callers should check rules.Validate first.
+func NewWithRules(rng Source, rules Rules) *Game {
+	g := &Game{rng: rng, Rules: rules}
+	g.SpawnTile()
+	g.SpawnTile()
+	return g
+}
+
+// NewOnBoard starts a game from start, which may hold obstacles and tiles,
+// and spawns two tiles on it according to rules
+func NewOnBoard(rng Source, rules Rules, start Board) *Game {
+	g := &Game{rng: rng, Rules: rules, Board: start}
+	g.SpawnTile()
+	g.SpawnTile()
+	return g
+}
+
+// NewScripted starts a game on start, exactly as given, that spawns tiles
+// from script rather than at random
+func NewScripted(rules Rules, start Board, script []Spawn) *Game {
+	return &Game{
+		Board:    start,
+		Rules:    rules,
+		Scripted: true,
+		Script:   append([]Spawn(nil), script...),
+	}
+}
+
+// SetSource replaces the randomness used for future spawns, e.g. after a game
+// has been deserialized. A nil rng uses math/rand.
+func (g *Game) SetSource(rng Source) {
+	g.rng = rng
+}
+
+func (g *Game) source() Source {
+	if g.rng == nil {
+		return globalSource{}
+	}
+	return g.rng
+}
+
+// Clone returns an independent copy of g that shares its random source
+func (g *Game) Clone() *Game {
+	c := *g
+	return &c
+}
+
+// MoveResult describes what a move did, so it can be animated or relayed
+type MoveResult struct {
+	Direction Direction `json:"direction"`
+	Gained    int       `json:"gained"`
+	Merged    [][2]int  `json:"merged"`  // cells holding a newly merged tile
+	Spawned   []Spawn   `json:"spawned"` // tiles spawned after the slide
+}
+
+// Move slides the board in dir. If any tile moved, new tiles are spawned and
+// the win and game over flags are updated. It reports whether the board changed.
+func (g *Game) Move(dir Direction) bool {
+	_, moved := g.Play(dir)
+	return moved
+}
+
+// Play is Move that also reports the merges and spawns of the move
+func (g *Game) Play(dir Direction) (MoveResult, bool) {
+	result := MoveResult{Direction: dir, Merged: [][2]int{}, Spawned: []Spawn{}}
+	if g.GameOver || !dir.Valid() {
+		return result, false
+	}
+
+	var merged Board
+	board, gained, moved := slideTracked(g.Board, dir, g.Rules.MergeRule(), &merged)
+	if !moved {
+		return result, false
+	}
+
+	g.Board = board
+	g.Score += gained
+	for i := 0; i < g.Rules.tilesPerMove(); i++ {
+		g.SpawnTile()
+	}
+	if !g.Won && g.Board.MaxTile() >= g.Rules.MergeRule().WinningTile() {
+		g.Won = true
+	}
+	if !g.Rules.CanMove(g.Board) {
+		g.GameOver = true
+	}
+
+	result.Gained = gained
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			if merged[r][c] != 0 {
+				result.Merged = append(result.Merged, [2]int{r, c})
+			}
+			if board[r][c] == 0 && g.Board[r][c] != 0 {
+				result.Spawned = append(result.Spawned, Spawn{Value: int(g.Board[r][c]), Row: r, Col: c})
+			}
+		}
+	}
+	return result, true
+}
+
+// Legal returns the directions that would change the board
+func (g *Game) Legal() []Direction {
+	if g.GameOver {
+		return nil
+	}
+	var legal []Direction
+	for _, dir := range Directions {
+		if _, _, moved := g.Rules.Slide(g.Board, dir); moved {
+			legal = append(legal, dir)
+		}
+	}
+	return legal
+}
+
+// SpawnTile places one new tile according to the game's rules: by default a
+// 2 (90%) or a 4 (10%) on a random empty cell in classic games. Scripted
+// games take the next tile from their script.
+func (g *Game) SpawnTile() {
+	if g.Scripted {
+		g.spawnScripted()
+		return
+	}
+	if g.Rules.Adversarial {
+		if pos, val, ok := g.Rules.worstSpawn(g.Board); ok {
+			g.Board[pos[0]][pos[1]] = Tile(val)
+		}
+		return
+	}
+
+	empty := g.Board.EmptyCells()
+	if len(empty) == 0 {
+		return
+	}
+	rng := g.source()
+	pos := empty[rng.Intn(len(empty))]
+	g.Board[pos[0]][pos[1]] = Tile(g.Rules.spawnValue(rng))
+}
+
+// spawnScripted places the next scripted tile, if any are left
+func (g *Game) spawnScripted() {
+	if g.ScriptPos >= len(g.Script) {
+		return
+	}
+	empty := g.Board.EmptyCells()
+	if len(empty) == 0 {
+		return
+	}
+	next := g.Script[g.ScriptPos]
+	g.ScriptPos++
+
+	pos := empty[0]
+	if next.Row >= 0 && next.Row < Size && next.Col >= 0 && next.Col < Size && g.Board[next.Row][next.Col] == 0 {
+		pos = [2]int{next.Row, next.Col}
+	}
+	g.Board[pos[0]][pos[1]] = Tile(next.Value)
+}
@@ -0,0 +1,350 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+)
+
+const B = Blocker
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  Rules
+		board  Board
+		dir    Direction
+		want   Board
+		gained int
+		moved  bool
+	}{
+		// Left
+		{name: "left slides into gaps", dir: Left,
+			board: Board{{0, 2, 0, 4}},
+			want:  Board{{2, 4, 0, 0}}, moved: true},
+		{name: "left merges a pair across a gap", dir: Left,
+			board: Board{{2, 0, 0, 2}},
+			want:  Board{{4, 0, 0, 0}}, gained: 4, moved: true},
+		{name: "left doesn't merge a merged tile again", dir: Left,
+			board: Board{{2, 2, 4, 0}},
+			want:  Board{{4, 4, 0, 0}}, gained: 4, moved: true},
+		{name: "left merges two pairs", dir: Left,
+			board: Board{{2, 2, 2, 2}},
+			want:  Board{{4, 4, 0, 0}}, gained: 8, moved: true},
+		{name: "left merges the leading pair of three", dir: Left,
+			board: Board{{2, 2, 2, 0}},
+			want:  Board{{4, 2, 0, 0}}, gained: 4, moved: true},
+		{name: "left with nothing to do", dir: Left,
+			board: Board{{2, 4, 8, 16}},
+			want:  Board{{2, 4, 8, 16}}},
+
+		// Right
+		{name: "right merges the leading pair of three", dir: Right,
+			board: Board{{2, 2, 2, 0}},
+			want:  Board{{0, 0, 2, 4}}, gained: 4, moved: true},
+		{name: "right merges two pairs", dir: Right,
+			board: Board{{4, 4, 8, 8}},
+			want:  Board{{0, 0, 8, 16}}, gained: 24, moved: true},
+		{name: "right doesn't merge a merged tile again", dir: Right,
+			board: Board{{0, 4, 2, 2}},
+			want:  Board{{0, 0, 4, 4}}, gained: 4, moved: true},
+
+		// Up
+		{name: "up doesn't merge a merged tile again", dir: Up,
+			board: Board{{2}, {2}, {4}, {0}},
+			want:  Board{{4}, {4}, {0}, {0}}, gained: 4, moved: true},
+		{name: "up slides every column", dir: Up,
+			board: Board{{0, 0, 0, 0}, {2, 0, 0, 8}, {0, 4, 0, 0}, {0, 0, 2, 8}},
+			want:  Board{{2, 4, 2, 16}, {0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}}, gained: 16, moved: true},
+
+		// Down
+		{name: "down merges two pairs", dir: Down,
+			board: Board{{2}, {2}, {2}, {2}},
+			want:  Board{{0}, {0}, {4}, {4}}, gained: 8, moved: true},
+		{name: "down with nothing to do", dir: Down,
+			board: Board{{2}, {4}, {8}, {16}},
+			want:  Board{{2}, {4}, {8}, {16}}},
+
+		// Blockers
+		{name: "blocker splits a row into segments", dir: Left,
+			board: Board{{0, 2, B, 2}},
+			want:  Board{{2, 0, B, 2}}, moved: true},
+		{name: "tiles don't merge across a blocker", dir: Left,
+			board: Board{{2, B, 0, 2}},
+			want:  Board{{2, B, 2, 0}}, moved: true},
+		{name: "blocker stays put", dir: Right,
+			board: Board{{B, 2, 0, 0}},
+			want:  Board{{B, 0, 0, 2}}, moved: true},
+		{name: "blocker in a column", dir: Up,
+			board: Board{{0}, {B}, {2}, {2}},
+			want:  Board{{0}, {B}, {4}, {0}}, gained: 4, moved: true},
+		{name: "tiles walled in by blockers can't move", dir: Left,
+			board: Board{{B, 2, B, 4}},
+			want:  Board{{B, 2, B, 4}}},
+
+		// Rocks
+		{name: "merge next to a rock chips it", dir: Left,
+			board: Board{{Rock(2), 2, 2, 0}},
+			want:  Board{{Rock(1), 4, 0, 0}}, gained: 4, moved: true},
+		{name: "last hit clears a rock", dir: Left,
+			board: Board{{Rock(1), 2, 2, 0}},
+			want:  Board{{0, 4, 0, 0}}, gained: 4, moved: true},
+		{name: "merge chips a rock in the next row", dir: Left,
+			board: Board{{2, 2, 0, 0}, {Rock(1), 0, 0, 0}},
+			want:  Board{{4, 0, 0, 0}, {0, 0, 0, 0}}, gained: 4, moved: true},
+		{name: "sliding without merging leaves rocks alone", dir: Left,
+			board: Board{{Rock(1), 0, 2, 0}},
+			want:  Board{{Rock(1), 2, 0, 0}}, moved: true},
+
+		// Variants
+		{name: "strict merges once per line", rules: Rules{Variant: VariantStrict}, dir: Left,
+			board: Board{{2, 2, 2, 2}},
+			want:  Board{{4, 2, 2, 0}}, gained: 4, moved: true},
+		{name: "fibonacci merges neighbours", rules: Rules{Variant: VariantFibonacci}, dir: Left,
+			board: Board{{1, 1, 2, 0}},
+			want:  Board{{2, 2, 0, 0}}, gained: 2, moved: true},
+		{name: "fibonacci merges consecutive numbers", rules: Rules{Variant: VariantFibonacci}, dir: Right,
+			board: Board{{0, 3, 5, 13}},
+			want:  Board{{0, 0, 8, 13}}, gained: 8, moved: true},
+		{name: "powers of three merge in threes", rules: Rules{Variant: VariantThree}, dir: Left,
+			board: Board{{3, 3, 3, 3}},
+			want:  Board{{9, 3, 0, 0}}, gained: 9, moved: true},
+		{name: "powers of three don't merge pairs", rules: Rules{Variant: VariantThree}, dir: Left,
+			board: Board{{3, 3, 9, 9}},
+			want:  Board{{3, 3, 9, 9}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gained, moved := tt.rules.Slide(tt.board, tt.dir)
+			if got != tt.want || gained != tt.gained || moved != tt.moved {
+				t.Errorf("Slide(%v, %s) = %v, %d, %v; want %v, %d, %v",
+					tt.board, tt.dir, got, gained, moved, tt.want, tt.gained, tt.moved)
+			}
+
+			// A scripted game with an empty script spawns nothing, so Move
+			// leaves exactly the slid board
+			g := NewScripted(tt.rules, tt.board, nil)
+			if moved := g.Move(tt.dir); moved != tt.moved || g.Board != tt.want || g.Score != tt.gained {
+				t.Errorf("Move(%s) = %v with board %v and score %d; want %v, %v and %d",
+					tt.dir, moved, g.Board, g.Score, tt.moved, tt.want, tt.gained)
+			}
+		})
+	}
+}
+
+func TestMoveIgnoresInvalidDirections(t *testing.T) {
+	g := NewScripted(Rules{}, Board{{0, 2}}, nil)
+	if g.Move("sideways") {
+		t.Error("Move(sideways) reported a move")
+	}
+	if want := (Board{{0, 2}}); g.Board != want {
+		t.Errorf("board = %v, want %v", g.Board, want)
+	}
+}
+
+func TestLegal(t *testing.T) {
+	tests := []struct {
+		name  string
+		board Board
+		want  []Direction
+	}{
+		{name: "single tile in a corner",
+			board: Board{{2}},
+			want:  []Direction{Down, Right}},
+		{name: "full board with a pair",
+			board: Board{{2, 2, 4, 8}, {4, 8, 16, 32}, {8, 16, 32, 64}, {16, 32, 64, 128}},
+			want:  []Direction{Left, Right}},
+		{name: "full board without pairs",
+			board: Board{{2, 4, 2, 4}, {4, 2, 4, 2}, {2, 4, 2, 4}, {4, 2, 4, 2}},
+			want:  nil},
+		{name: "tile walled in by blockers",
+			board: Board{{2, B}, {B, 0}},
+			want:  nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewScripted(Rules{}, tt.board, nil)
+			got := g.Legal()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Legal() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Legal() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// Property tests. Each property plays random games from a seed chosen by
+// testing/quick, so a failure reports the seed that reproduces it.
+
+// tileValues returns the smallest tiles of a merge rule, so that random
+// boards have plenty of merges
+func tileValues(merge MergeRule) []int {
+	var values []int
+	for v := 1; len(values) < 8; v++ {
+		if merge.ValidTile(v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// randomGame starts a game with random rules on a random board, which may
+// hold obstacles
+func randomGame(rng *rand.Rand) *Game {
+	variants := Variants()
+	rules := Rules{
+		Variant:      variants[rng.Intn(len(variants))],
+		TilesPerMove: 1 + rng.Intn(2),
+		Adversarial:  rng.Intn(8) == 0,
+	}
+	values := tileValues(rules.MergeRule())
+	obstacles := rng.Intn(2) == 0
+
+	var b Board
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			switch x := rng.Float64(); {
+			case obstacles && x < 0.05:
+				b[r][c] = Blocker
+			case obstacles && x < 0.1:
+				b[r][c] = Rock(1 + rng.Intn(3))
+			case x < 0.4:
+			default:
+				b[r][c] = Tile(values[rng.Intn(len(values))])
+			}
+		}
+	}
+
+	g := &Game{Board: b, Rules: rules}
+	g.SetSource(rand.New(rand.NewSource(rng.Int63())))
+	g.GameOver = !rules.CanMove(b)
+	return g
+}
+
+// tileSum adds up the tiles on b, ignoring obstacles
+func tileSum(b Board) int {
+	sum := 0
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			if !b[r][c].IsObstacle() {
+				sum += int(b[r][c])
+			}
+		}
+	}
+	return sum
+}
+
+// checkGames plays up to 100 random moves in a random game for each seed
+// testing/quick picks, checking property before each one
+func checkGames(t *testing.T, property func(g *Game, dir Direction) bool) {
+	t.Helper()
+	f := func(seed int64) bool {
+		rng := rand.New(rand.NewSource(seed))
+		g := randomGame(rng)
+		for i := 0; i < 100 && !g.GameOver; i++ {
+			dir := Directions[rng.Intn(len(Directions))]
+			if !property(g, dir) {
+				t.Logf("seed %d, move %d (%s), board %v", seed, i, dir, g.Board)
+				return false
+			}
+			g.Move(dir)
+		}
+		return true
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 300}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyScoreNeverDecreases(t *testing.T) {
+	checkGames(t, func(g *Game, dir Direction) bool {
+		before := g.Score
+		result, moved := g.Clone().Play(dir)
+		after := g.Clone()
+		after.Move(dir)
+		if !moved {
+			return after.Score == before && result.Gained == 0
+		}
+		return after.Score == before+result.Gained && result.Gained >= 0
+	})
+}
+
+func TestPropertyTileSumConserved(t *testing.T) {
+	checkGames(t, func(g *Game, dir Direction) bool {
+		before := tileSum(g.Board)
+		next := g.Clone()
+		result, moved := next.Play(dir)
+		if !moved {
+			return tileSum(next.Board) == before && len(result.Spawned) == 0
+		}
+
+		// Merges keep the sum of tiles; only spawns add to it
+		spawned := 0
+		for _, s := range result.Spawned {
+			spawned += s.Value
+		}
+		slid, _, _ := g.Rules.Slide(g.Board, dir)
+		return tileSum(slid) == before &&
+			tileSum(next.Board) == before+spawned &&
+			len(result.Spawned) <= g.Rules.tilesPerMove()
+	})
+}
+
+func TestPropertyCloneIsIndependent(t *testing.T) {
+	checkGames(t, func(g *Game, dir Direction) bool {
+		board, score, won, over := g.Board, g.Score, g.Won, g.GameOver
+		clone := g.Clone()
+		for _, d := range Directions {
+			clone.Move(d)
+		}
+		clone.Board[0][0] = 2048
+		clone.Score += 1000
+		return g.Board == board && g.Score == score && g.Won == won && g.GameOver == over
+	})
+}
+
+func TestPropertyLegalAgreesWithMove(t *testing.T) {
+	checkGames(t, func(g *Game, dir Direction) bool {
+		legal := make(map[Direction]bool)
+		for _, d := range g.Legal() {
+			legal[d] = true
+		}
+		for _, d := range Directions {
+			if g.Clone().Move(d) != legal[d] {
+				return false
+			}
+		}
+		if len(legal) == 0 {
+			return false // the game should have been over
+		}
+
+		// The game is over exactly when nothing is legal
+		next := g.Clone()
+		next.Move(dir)
+		anyMove := false
+		for _, d := range Directions {
+			if _, _, moved := next.Rules.Slide(next.Board, d); moved {
+				anyMove = true
+			}
+		}
+		return next.GameOver == !anyMove && (len(next.Legal()) > 0) == anyMove
+	})
+}
+
+func TestScriptedCloneIsIndependent(t *testing.T) {
+	g := NewScripted(Rules{}, Board{{2}}, []Spawn{{Value: 4, Row: 3, Col: 3}})
+	clone := g.Clone()
+	clone.Move(Right)
+	if g.ScriptPos != 0 || g.Board != (Board{{2}}) {
+		t.Errorf("moving a clone changed the original: script position %d, board %v", g.ScriptPos, g.Board)
+	}
+	if clone.ScriptPos != 1 || clone.Board[3][3] != 4 {
+		t.Errorf("clone didn't spawn from the script: script position %d, board %v", clone.ScriptPos, clone.Board)
+	}
+}
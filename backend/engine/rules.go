@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SpawnWeight is the relative chance of spawning a tile with Value
+type SpawnWeight struct {
+	Value  int     `json:"value"`
+	Weight float64 `json:"weight"`
+}
+
+// Rules are the tunable parameters of a game. The zero value is classic
+// 2048: one tile per move, 90% 2 and 10% 4, placed at random.
+type Rules struct {
+	// Variant selects the merge rule and win condition (default "classic")
+	Variant string `json:"variant,omitempty"`
+	// SpawnWeights overrides the classic 2/4 distribution
+	SpawnWeights []SpawnWeight `json:"spawnWeights,omitempty"`
+	// TilesPerMove is how many tiles spawn after each move (default 1)
+	TilesPerMove int `json:"tilesPerMove,omitempty"`
+	// Adversarial places each new tile where it hurts the player most
+	Adversarial bool `json:"adversarial,omitempty"`
+}
+
+// MaxTilesPerMove bounds Rules.TilesPerMove
+const MaxTilesPerMove = 4
+
+// Validate reports whether r describes a playable game
+func (r Rules) Validate() error {
+	if _, ok := mergeRules[r.variantName()]; !ok {
+		return fmt.Errorf("unknown variant %q (want one of %v)", r.Variant, Variants())
+	}
+	merge := r.MergeRule()
+	if r.TilesPerMove < 0 || r.TilesPerMove > MaxTilesPerMove {
+		return fmt.Errorf("tilesPerMove must be between 0 (the default, 1) and %d", MaxTilesPerMove)
+	}
+	if len(r.SpawnWeights) > 8 {
+		return fmt.Errorf("at most 8 spawn weights are allowed")
+	}
+	total := 0.0
+	seen := make(map[int]bool)
+	for _, w := range r.SpawnWeights {
+		if !merge.ValidTile(w.Value) || w.Value >= merge.WinningTile() {
+			return fmt.Errorf("spawn value %d is not a %s tile below %d", w.Value, r.variantName(), merge.WinningTile())
+		}
+		if seen[w.Value] {
+			return fmt.Errorf("spawn value %d is listed twice", w.Value)
+		}
+		seen[w.Value] = true
+		if w.Weight < 0 {
+			return fmt.Errorf("spawn weight for %d must not be negative", w.Value)
+		}
+		total += w.Weight
+	}
+	if len(r.SpawnWeights) > 0 && total <= 0 {
+		return fmt.Errorf("spawn weights must add up to more than zero")
+	}
+	return nil
+}
+
+// IsClassic reports whether r are the standard rules
+func (r Rules) IsClassic() bool {
+	return r.variantName() == VariantClassic && len(r.SpawnWeights) == 0 && r.TilesPerMove <= 1 && !r.Adversarial
+}
+
+func (r Rules) variantName() string {
+	if r.Variant == "" {
+		return VariantClassic
+	}
+	return r.Variant
+}
+
+// MergeRule returns the merge rule for r's variant, falling back to classic
+// for unknown variants
+func (r Rules) MergeRule() MergeRule {
+	if m, ok := mergeRules[r.variantName()]; ok {
+		return m
+	}
+	return classicMerge{}
+}
+
+// Slide moves every tile on b in dir using r's merge rule. It returns the new
+// board, the points scored by merges and whether anything moved.
+func (r Rules) Slide(b Board, dir Direction) (Board, int, bool) {
+	return slide(b, dir, r.MergeRule())
+}
+
+// CanMove reports whether any move is possible on b under r
+func (r Rules) CanMove(b Board) bool {
+	if r.variantName() == VariantClassic || r.variantName() == VariantStrict {
+		return b.CanMove()
+	}
+	for _, dir := range Directions {
+		if _, _, moved := r.Slide(b, dir); moved {
+			return true
+		}
+	}
+	return false
+}
+
+// Name is a short label for r, used to tell leaderboard entries apart, such
+// as "classic", "fibonacci" or "adversarial+spawn-2x+spawn(2:0.8,8:0.2)"
+func (r Rules) Name() string {
+	if r.IsClassic() {
+		return VariantClassic
+	}
+	var parts []string
+	if r.variantName() != VariantClassic {
+		parts = append(parts, r.variantName())
+	}
+	if r.Adversarial {
+		parts = append(parts, "adversarial")
+	}
+	if r.TilesPerMove > 1 {
+		parts = append(parts, fmt.Sprintf("spawn-%dx", r.TilesPerMove))
+	}
+	if len(r.SpawnWeights) > 0 {
+		weights := append([]SpawnWeight(nil), r.SpawnWeights...)
+		sort.Slice(weights, func(i, j int) bool { return weights[i].Value < weights[j].Value })
+		total := 0.0
+		for _, w := range weights {
+			total += w.Weight
+		}
+		desc := make([]string, 0, len(weights))
+		for _, w := range weights {
+			if w.Weight > 0 {
+				desc = append(desc, fmt.Sprintf("%d:%g", w.Value, w.Weight/total))
+			}
+		}
+		parts = append(parts, "spawn("+strings.Join(desc, ",")+")")
+	}
+	return strings.Join(parts, "+")
+}
+
+func (r Rules) tilesPerMove() int {
+	if r.TilesPerMove < 1 {
+		return 1
+	}
+	return r.TilesPerMove
+}
+
+// spawnValue picks a tile value. The default distribution draws from rng in
+// the same way as the original engine (and Bitboard.SpawnTile).
+func (r Rules) spawnValue(rng Source) int {
+	if len(r.SpawnWeights) == 0 {
+		common, rare := r.MergeRule().DefaultSpawn()
+		if rng.Float64() < 0.1 {
+			return rare
+		}
+		return common
+	}
+
+	total := 0.0
+	for _, w := range r.SpawnWeights {
+		total += w.Weight
+	}
+	x := rng.Float64() * total
+	for _, w := range r.SpawnWeights {
+		if w.Weight <= 0 {
+			continue
+		}
+		if x < w.Weight {
+			return w.Value
+		}
+		x -= w.Weight
+	}
+	return r.SpawnWeights[len(r.SpawnWeights)-1].Value
+}
+
+// spawnValues lists the values that can spawn, for the adversary to choose from
+func (r Rules) spawnValues() []int {
+	if len(r.SpawnWeights) == 0 {
+		common, rare := r.MergeRule().DefaultSpawn()
+		return []int{common, rare}
+	}
+	values := make([]int, 0, len(r.SpawnWeights))
+	for _, w := range r.SpawnWeights {
+		if w.Weight > 0 {
+			values = append(values, w.Value)
+		}
+	}
+	return values
+}
+
+// worstSpawn returns the placement that leaves the player worst off: the
+// fewest legal moves, then the fewest empty cells after their best reply.
+func (r Rules) worstSpawn(b Board) (pos [2]int, value int, ok bool) {
+	values := r.spawnValues()
+	bestLegal, bestEmpty := Size*Size+1, Size*Size+1
+	for _, cell := range b.EmptyCells() {
+		for _, v := range values {
+			candidate := b
+			candidate[cell[0]][cell[1]] = Tile(v)
+
+			legal, empty := 0, 0
+			for _, dir := range Directions {
+				next, _, moved := r.Slide(candidate, dir)
+				if !moved {
+					continue
+				}
+				legal++
+				if n := len(next.EmptyCells()); n > empty {
+					empty = n
+				}
+			}
+
+			if legal < bestLegal || (legal == bestLegal && empty < bestEmpty) {
+				bestLegal, bestEmpty = legal, empty
+				pos, value, ok = cell, v, true
+			}
+		}
+	}
+	return pos, value, ok
+}
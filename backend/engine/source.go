@@ -0,0 +1,42 @@
+package engine
+
+import "hash/fnv"
+
+// BoardSource returns a Source determined only by seed and b. Games that
+// share a seed get the same tiles whenever they reach the same position,
+// and nothing but the seed has to be kept between moves.
+func BoardSource(seed int64, b Board) Source {
+	h := fnv.New64a()
+	var buf [8]byte
+	for r := 0; r < Size; r++ {
+		for c := 0; c < Size; c++ {
+			v := uint64(b[r][c])
+			for i := range buf {
+				buf[i] = byte(v >> (8 * i))
+			}
+			h.Write(buf[:])
+		}
+	}
+	return &splitMix{state: uint64(seed) ^ h.Sum64()}
+}
+
+// splitMix is the SplitMix64 generator: tiny, fast and good enough for spawns
+type splitMix struct {
+	state uint64
+}
+
+func (s *splitMix) next() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *splitMix) Intn(n int) int {
+	return int(s.next() % uint64(n))
+}
+
+func (s *splitMix) Float64() float64 {
+	return float64(s.next()>>11) / (1 << 53)
+}
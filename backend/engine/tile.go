@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Tile is the content of one cell: 0 when empty, a positive tile value, or
+// an obstacle. Obstacles never move or merge, and tiles can't slide past them.
+//
+// Obstacles are stored as negative numbers so a Board stays a plain array
+// that can be copied and compared; use the methods rather than the encoding.
+type Tile int
+
+// Blocker is an obstacle that stays put for the whole game
+const Blocker Tile = -1
+
+// MaxRockHits bounds the number of merges a rock can take
+const MaxRockHits = 16
+
+// Rock returns a rock that crumbles after hits merges happen next to it
+func Rock(hits int) Tile {
+	return Tile(-1 - hits)
+}
+
+// IsObstacle reports whether t is a blocker or a rock
+func (t Tile) IsObstacle() bool { return t < 0 }
+
+// IsBlocker reports whether t is a blocker
+func (t Tile) IsBlocker() bool { return t == Blocker }
+
+// IsRock reports whether t is a rock
+func (t Tile) IsRock() bool { return t < Blocker }
+
+// RockHits returns how many more merges next to the rock it takes to clear it
+func (t Tile) RockHits() int {
+	if !t.IsRock() {
+		return 0
+	}
+	return int(-t) - 1
+}
+
+// hit removes one hit from a rock, leaving an empty cell once none are left
+func (t Tile) hit() Tile {
+	if t.RockHits() <= 1 {
+		return 0
+	}
+	return t + 1
+}
+
+// obstacleJSON is how obstacles appear in a board. Numbers are tiles, so
+// clients can tell the two apart by type.
+type obstacleJSON struct {
+	Type string `json:"type"` // "blocker" or "rock"
+	Hits int    `json:"hits,omitempty"`
+}
+
+// MarshalJSON encodes tiles and empty cells as numbers, and obstacles as
+// objects such as {"type":"blocker"} or {"type":"rock","hits":2}
+func (t Tile) MarshalJSON() ([]byte, error) {
+	switch {
+	case t.IsBlocker():
+		return json.Marshal(obstacleJSON{Type: "blocker"})
+	case t.IsRock():
+		return json.Marshal(obstacleJSON{Type: "rock", Hits: t.RockHits()})
+	}
+	return json.Marshal(int(t))
+}
+
+// UnmarshalJSON accepts the encodings produced by MarshalJSON
+func (t *Tile) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var o obstacleJSON
+		if err := json.Unmarshal(data, &o); err != nil {
+			return err
+		}
+		obstacle, err := ParseObstacle(o.Type, o.Hits)
+		if err != nil {
+			return err
+		}
+		*t = obstacle
+		return nil
+	}
+
+	var v int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("tile value %d must not be negative", v)
+	}
+	*t = Tile(v)
+	return nil
+}
+
+// ParseObstacle returns the obstacle named kind ("blocker" or "rock"). Rocks
+// need between 1 and MaxRockHits hits.
+func ParseObstacle(kind string, hits int) (Tile, error) {
+	switch kind {
+	case "blocker":
+		return Blocker, nil
+	case "rock":
+		if hits < 1 || hits > MaxRockHits {
+			return 0, fmt.Errorf("rock hits must be between 1 and %d", MaxRockHits)
+		}
+		return Rock(hits), nil
+	}
+	return 0, fmt.Errorf("unknown obstacle type %q", kind)
+}
@@ -0,0 +1,164 @@
+package engine
+
+import "sort"
+
+// MergeRule decides which tiles combine when a line is slid, and what it
+// takes to win. Implementations must be stateless.
+type MergeRule interface {
+	// Merge combines a line of non-zero tiles, already packed towards the
+	// front, returning the merged line, a bitmask of the positions in it that
+	// hold a newly merged tile and the points scored
+	Merge(tiles []int) ([]int, uint, int)
+	// ValidTile reports whether v can appear on the board (and be spawned)
+	ValidTile(v int) bool
+	// DefaultSpawn returns the usual spawned tile and the rarer one (10%)
+	DefaultSpawn() (common, rare int)
+	// WinningTile is the tile that wins the game
+	WinningTile() int
+}
+
+// Variant names accepted in Rules.Variant
+const (
+	VariantClassic   = "classic"
+	VariantStrict    = "strict"
+	VariantFibonacci = "fibonacci"
+	VariantThree     = "powers-of-three"
+)
+
+var mergeRules = map[string]MergeRule{
+	VariantClassic:   classicMerge{},
+	VariantStrict:    strictMerge{},
+	VariantFibonacci: fibonacciMerge{},
+	VariantThree:     threeMerge{},
+}
+
+// Variants returns the names of every merge rule, sorted
+func Variants() []string {
+	names := make([]string, 0, len(mergeRules))
+	for name := range mergeRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// classicMerge is standard 2048: two equal tiles double, and a merged tile
+// can't merge again in the same move
+type classicMerge struct{}
+
+func (classicMerge) Merge(temp []int) ([]int, uint, int) {
+	score := 0
+	var merged uint
+	for j := 0; j < len(temp)-1; j++ {
+		if temp[j] == temp[j+1] {
+			temp[j] *= 2
+			score += temp[j]
+			merged |= 1 << j
+			temp = append(temp[:j+1], temp[j+2:]...)
+		}
+	}
+	return temp, merged, score
+}
+
+func (classicMerge) ValidTile(v int) bool     { return isPowerOf(v, 2) }
+func (classicMerge) DefaultSpawn() (int, int) { return 2, 4 }
+func (classicMerge) WinningTile() int         { return WinningTile }
+
+// strictMerge is classic 2048 where each line merges at most once per move,
+// so [2 2 2 2] slides to [4 2 2 0] instead of [4 4 0 0]
+type strictMerge struct{}
+
+func (strictMerge) Merge(temp []int) ([]int, uint, int) {
+	for j := 0; j < len(temp)-1; j++ {
+		if temp[j] == temp[j+1] {
+			temp[j] *= 2
+			return append(temp[:j+1], temp[j+2:]...), 1 << j, temp[j]
+		}
+	}
+	return temp, 0, 0
+}
+
+func (strictMerge) ValidTile(v int) bool     { return isPowerOf(v, 2) }
+func (strictMerge) DefaultSpawn() (int, int) { return 2, 4 }
+func (strictMerge) WinningTile() int         { return WinningTile }
+
+// fibonacciMerge combines neighbouring Fibonacci numbers (1+1, 1+2, 2+3,
+// 3+5, ...) into their sum
+type fibonacciMerge struct{}
+
+// fibonacci lists the Fibonacci tiles up to well past the winning tile
+var fibonacci = func() []int {
+	fib := []int{1, 2}
+	for fib[len(fib)-1] < 1<<30 {
+		fib = append(fib, fib[len(fib)-1]+fib[len(fib)-2])
+	}
+	return fib
+}()
+
+func fibonacciIndex(v int) int {
+	for i, f := range fibonacci {
+		if f == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func fibonacciPair(a, b int) bool {
+	if a == 1 && b == 1 {
+		return true
+	}
+	i, j := fibonacciIndex(a), fibonacciIndex(b)
+	return i >= 0 && j >= 0 && (i-j == 1 || j-i == 1)
+}
+
+func (fibonacciMerge) Merge(temp []int) ([]int, uint, int) {
+	score := 0
+	var merged uint
+	for j := 0; j < len(temp)-1; j++ {
+		if fibonacciPair(temp[j], temp[j+1]) {
+			temp[j] += temp[j+1]
+			score += temp[j]
+			merged |= 1 << j
+			temp = append(temp[:j+1], temp[j+2:]...)
+		}
+	}
+	return temp, merged, score
+}
+
+func (fibonacciMerge) ValidTile(v int) bool     { return fibonacciIndex(v) >= 0 }
+func (fibonacciMerge) DefaultSpawn() (int, int) { return 1, 2 }
+func (fibonacciMerge) WinningTile() int         { return 2584 }
+
+// threeMerge uses powers of three, and it takes three equal tiles in a row to
+// merge into one tile of three times the value
+type threeMerge struct{}
+
+func (threeMerge) Merge(temp []int) ([]int, uint, int) {
+	score := 0
+	var merged uint
+	for j := 0; j < len(temp)-2; j++ {
+		if temp[j] == temp[j+1] && temp[j] == temp[j+2] {
+			temp[j] *= 3
+			score += temp[j]
+			merged |= 1 << j
+			temp = append(temp[:j+1], temp[j+3:]...)
+		}
+	}
+	return temp, merged, score
+}
+
+func (threeMerge) ValidTile(v int) bool     { return v >= 3 && isPowerOf(v, 3) }
+func (threeMerge) DefaultSpawn() (int, int) { return 3, 9 }
+func (threeMerge) WinningTile() int         { return 2187 }
+
+// isPowerOf reports whether v is a positive power of base (base^1 or higher)
+func isPowerOf(v, base int) bool {
+	if v < base {
+		return false
+	}
+	for v%base == 0 {
+		v /= base
+	}
+	return v == 1
+}
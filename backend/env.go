@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// durationFromEnv parses a duration from the environment, falling back to def
+func durationFromEnv(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
+}
+
+// intFromEnv parses a positive integer from the environment, falling back to def
+func intFromEnv(name string, def int) int {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"io"
 	"net/http"
-	"strconv"
 	"time"
+
+	"2048game/engine"
 )
 
 func healthHandler(w http.ResponseWriter, r *http.Request) {
@@ -20,22 +23,62 @@ func newGameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id := generateID()
-	game := &GameState{
-		ID:        id,
-		CreatedAt: time.Now(),
+	// The body is optional; without one a classic game is started. A level
+	// brings its own rules and starting board.
+	var req struct {
+		Rules      engine.Rules `json:"rules"`
+		Level      string       `json:"level"`
+		PlayerID   string       `json:"playerId"` // credited with puzzle results and achievements
+		TimeAttack bool         `json:"timeAttack"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var game *GameState
+	if req.Level != "" {
+		level, ok := levels[req.Level]
+		if !ok {
+			http.Error(w, "Unknown level", http.StatusNotFound)
+			return
+		}
+		if !req.Rules.IsClassic() {
+			http.Error(w, "A level can't be combined with custom rules", http.StatusBadRequest)
+			return
+		}
+		var err error
+		if game, err = newLevelGameState(level, req.PlayerID); err != nil {
+			http.Error(w, "Invalid level", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if err := req.Rules.Validate(); err != nil {
+			http.Error(w, "Invalid rules: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		game = newGameState(req.Rules)
+	}
+	if req.TimeAttack {
+		if game.Puzzle != nil {
+			http.Error(w, "Puzzles can't be played as time attack", http.StatusBadRequest)
+			return
+		}
+		// The clock starts now, on the server
+		game.TimeAttack = newTimeAttackState(game.CreatedAt)
 	}
-	spawnTile(game)
-	spawnTile(game)
+	id := game.ID
+
+	logger := loggerFromContext(r.Context())
 
 	// Save game session to DynamoDB
-	if err := saveGameSession(game); err != nil {
-		log.Printf("Failed to save game session: %v", err)
+	if err := saveGameSession(r.Context(), game); err != nil {
+		logger.Error("failed to save game session", "game_id", id, "error", err)
 		http.Error(w, "Failed to create game", http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("New game created: %s", id)
+	logger.Info("new game created", "game_id", id, "mode", gameMode(game))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(game)
 }
@@ -50,27 +93,26 @@ func moveHandler(w http.ResponseWriter, r *http.Request) {
 		ID        string `json:"id"`
 		Direction string `json:"direction"`
 	}
+	logger := loggerFromContext(r.Context())
+
 	var req MoveRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Invalid move request: %v", err)
+		logger.Warn("invalid move request", "error", err)
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
 	// Validate direction
-	validDirections := map[string]bool{
-		"up": true, "down": true, "left": true, "right": true,
-	}
-	if !validDirections[req.Direction] {
+	dir := engine.Direction(req.Direction)
+	if !dir.Valid() {
 		http.Error(w, "Invalid direction", http.StatusBadRequest)
 		return
 	}
 
 	// Load game session from DynamoDB
-	game, err := loadGameSession(req.ID)
+	game, err := loadGameSession(r.Context(), req.ID)
 	if err != nil {
-		log.Printf("Game not found: %s, error: %v", req.ID, err)
-		http.Error(w, "Game not found", http.StatusNotFound)
+		writeSessionLoadError(w, r, req.ID, err)
 		return
 	}
 
@@ -79,24 +121,99 @@ func moveHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	moved := applyMove(game, req.Direction)
+	// A time-attack move that arrives after the deadline ends the game instead
+	now := time.Now()
+	if game.TimeAttack != nil && game.TimeAttack.expire(game, now) {
+		if err := saveGameSession(r.Context(), game); err != nil {
+			logger.Error("failed to save expired game session", "game_id", req.ID, "error", err)
+			http.Error(w, "Failed to save game state", http.StatusInternalServerError)
+			return
+		}
+		logger.Info("time attack expired", "game_id", req.ID, "score", game.Score)
+		game.Awards = awardAchievements(r.Context(), game, true)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(game)
+		return
+	}
+
+	// Races take their spawns from the room's seed, and stop with the room
+	if game.Race != nil {
+		ok, err := prepareRaceMove(r.Context(), game)
+		if err != nil {
+			writeRoomError(w, r, game.Race.RoomID, err)
+			return
+		}
+		if !ok {
+			if err := saveGameSession(r.Context(), game); err != nil {
+				logger.Error("failed to save finished race game", "game_id", req.ID, "error", err)
+			} else {
+				game.Awards = awardAchievements(r.Context(), game, true)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(game)
+			return
+		}
+	}
+
+	// Tournament games take their spawns from the round's seed, and stop with
+	// the round
+	if game.Tournament != nil {
+		ok, err := prepareTournamentMove(r.Context(), game)
+		if err != nil {
+			writeTournamentError(w, r, game.Tournament.TournamentID, err)
+			return
+		}
+		if !ok {
+			if err := saveGameSession(r.Context(), game); err != nil {
+				logger.Error("failed to save finished tournament game", "game_id", req.ID, "error", err)
+			} else {
+				game.Awards = awardAchievements(r.Context(), game, true)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(game)
+			return
+		}
+	}
+
+	result, moved := game.Play(dir)
 	if moved {
-		spawnTile(game)
-		checkWin(game)
-		if !canMove(game) {
-			game.GameOver = true
+		game.Moves++
+		game.LastMove = &result
+		newTile := game.noteMaxTile()
+		if game.Puzzle != nil {
+			game.Puzzle.afterMove(game)
+		}
+		if game.TimeAttack != nil {
+			game.TimeAttack.afterMove(game, now)
 		}
 
 		// Save updated game session to DynamoDB
-		if err := saveGameSession(game); err != nil {
-			log.Printf("Failed to save game session after move: %v", err)
+		if err := saveGameSession(r.Context(), game); err != nil {
+			logger.Error("failed to save game session after move", "game_id", req.ID, "error", err)
 			http.Error(w, "Failed to save game state", http.StatusInternalServerError)
 			return
 		}
 
-		log.Printf("Move applied for game %s: %s (Score: %d)", req.ID, req.Direction, game.Score)
+		logger.Debug("move applied", "game_id", req.ID, "direction", req.Direction, "score", game.Score)
+		recordPuzzleResult(r.Context(), game)
+		if game.Race != nil {
+			reportRaceProgress(r.Context(), game)
+		}
+		if game.Tournament != nil {
+			reportTournamentRun(r.Context(), game)
+		}
+		publishToSpectators(game)
+		if newTile || game.GameOver {
+			game.Awards = awardAchievements(r.Context(), game, game.GameOver)
+		}
+	}
+	if game.ShareToken != "" {
+		game.Spectators = countSpectators(r.Context(), game.ID)
 	}
 
+	if game.TimeAttack != nil {
+		game.TimeAttack.tick(now)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(game)
 }
@@ -114,19 +231,109 @@ func stateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Load game session from DynamoDB
-	game, err := loadGameSession(id)
+	game, err := loadGameSession(r.Context(), id)
 	if err != nil {
-		log.Printf("Game not found: %s, error: %v", id, err)
-		http.Error(w, "Game not found", http.StatusNotFound)
+		writeSessionLoadError(w, r, id, err)
 		return
 	}
 
+	if game.TimeAttack != nil {
+		now := time.Now()
+		if game.TimeAttack.expire(game, now) {
+			// Best effort: the next move would expire the game anyway, and
+			// award it then
+			if err := saveGameSession(r.Context(), game); err != nil {
+				loggerFromContext(r.Context()).Warn("failed to save expired game session", "game_id", id, "error", err)
+			} else {
+				game.Awards = awardAchievements(r.Context(), game, true)
+			}
+		}
+		game.TimeAttack.tick(now)
+	}
+	if game.ShareToken != "" {
+		game.Spectators = countSpectators(r.Context(), id)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(game)
 }
 
+// writeSessionLoadError maps a loadGameSession error to an HTTP response, so a
+// storage timeout isn't reported to the player as a missing game
+func writeSessionLoadError(w http.ResponseWriter, r *http.Request, gameID string, err error) {
+	logger := loggerFromContext(r.Context())
+
+	switch {
+	case errors.Is(err, errSessionNotFound):
+		logger.Warn("game not found", "game_id", gameID)
+		http.Error(w, "Game not found", http.StatusNotFound)
+	case errors.Is(err, context.Canceled):
+		// The client went away; nobody is listening for a response
+		logger.Debug("game load cancelled", "game_id", gameID)
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Error("game load timed out", "game_id", gameID, "error", err)
+		http.Error(w, "Storage timeout", http.StatusGatewayTimeout)
+	default:
+		logger.Error("failed to load game", "game_id", gameID, "error", err)
+		http.Error(w, "Failed to load game", http.StatusInternalServerError)
+	}
+}
+
+// writeLeaderboardError maps an error from reading the leaderboard to a
+// response
+func writeLeaderboardError(w http.ResponseWriter, r *http.Request, err error) {
+	logger := loggerFromContext(r.Context())
+
+	switch {
+	case errors.Is(err, errPlayerNotRanked):
+		http.Error(w, "Player not found", http.StatusNotFound)
+	case errors.Is(err, context.Canceled):
+		// The client went away; nobody is listening for a response
+		logger.Debug("leaderboard read cancelled")
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Error("leaderboard read timed out", "error", err)
+		http.Error(w, "Storage timeout", http.StatusGatewayTimeout)
+	default:
+		logger.Error("failed to read leaderboard", "error", err)
+		http.Error(w, "Failed to read leaderboard", http.StatusInternalServerError)
+	}
+}
+
 // Leaderboard Handlers
 
+// gameSubmissionTTL is how long a game is remembered as submitted, well past
+// the end of its session
+const gameSubmissionTTL = 24 * time.Hour
+
+// errAlreadySubmitted is returned by claimGameSubmission when the game's
+// score has been submitted before
+var errAlreadySubmitted = errors.New("game already submitted")
+
+// gameEntryID is the leaderboard entry ID of a game's score, so a score
+// written twice is still one entry
+func gameEntryID(gameID string) string {
+	return "game-" + gameID
+}
+
+// gameSubmittedKey marks a game's score as submitted in the sessions table
+func gameSubmittedKey(gameID string) string {
+	return "submitted#" + gameID
+}
+
+// claimGameSubmission records that gameID's score is being submitted, so
+// each game reaches the leaderboard once whichever pod is asked.
+// errAlreadySubmitted means another submission got there first.
+func claimGameSubmission(ctx context.Context, gameID string) error {
+	_, err := updateRecord(ctx, gameSubmittedKey(gameID), gameSubmissionTTL, func(entryID *string, exists bool) error {
+		if exists {
+			return errAlreadySubmitted
+		}
+		*entryID = gameEntryID(gameID)
+		return nil
+	})
+	return err
+}
+
 func submitScoreHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -134,6 +341,7 @@ func submitScoreHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type ScoreSubmission struct {
+		GameID   string `json:"gameId"`
 		PlayerID string `json:"playerId"`
 		Name     string `json:"name"`
 		Score    int    `json:"score"`
@@ -148,23 +356,107 @@ func submitScoreHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate submission
-	if submission.Name == "" || submission.Score <= 0 {
+	if submission.Score <= 0 {
 		http.Error(w, "Invalid submission data", http.StatusBadRequest)
 		return
 	}
+	name, err := normalizeName(submission.Name)
+	if err != nil {
+		http.Error(w, "Invalid name: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Every score needs a player ID, or leaving it out would get round bans
+	if submission.PlayerID == "" {
+		http.Error(w, "playerId is required", http.StatusBadRequest)
+		return
+	}
+	// and a game played on the server, or any score could be claimed
+	if submission.GameID == "" {
+		http.Error(w, "gameId is required", http.StatusBadRequest)
+		return
+	}
+	banned, err := playerBanned(r.Context(), submission.PlayerID)
+	if err != nil {
+		loggerFromContext(r.Context()).Error("failed to check player ban", "player_id", submission.PlayerID, "error", err)
+		http.Error(w, "Leaderboard temporarily unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if banned {
+		loggerFromContext(r.Context()).Info("rejected score from banned player", "player_id", submission.PlayerID)
+		http.Error(w, "Player is banned", http.StatusForbidden)
+		return
+	}
+
+	// The mode comes from the game session's rules
+	game, err := loadGameSession(r.Context(), submission.GameID)
+	if err != nil {
+		writeSessionLoadError(w, r, submission.GameID, err)
+		return
+	}
+	if game.PlayerID != "" && game.PlayerID != submission.PlayerID {
+		http.Error(w, "Game belongs to another player", http.StatusForbidden)
+		return
+	}
+	if submission.Score > game.Score {
+		http.Error(w, "Score exceeds game score", http.StatusBadRequest)
+		return
+	}
+	mode := gameMode(game)
+	maxTile, won := game.Board.MaxTile(), game.Won
+
+	// Time-attack results are taken from the server, not the client
+	if ta := game.TimeAttack; ta != nil {
+		ta.expire(game, time.Now())
+		submission.Score = game.Score
+		submission.Duration = int(ta.Duration().Seconds())
+		submission.Moves = ta.Moves
+	}
+	if !game.GameOver {
+		http.Error(w, "Game still in progress", http.StatusConflict)
+		return
+	}
+
+	switch err := claimGameSubmission(r.Context(), game.ID); {
+	case errors.Is(err, errAlreadySubmitted):
+		http.Error(w, "Game already submitted", http.StatusConflict)
+		return
+	case err != nil:
+		loggerFromContext(r.Context()).Error("failed to claim game submission", "game_id", game.ID, "error", err)
+		http.Error(w, "Leaderboard temporarily unavailable", http.StatusServiceUnavailable)
+		return
+	}
 
 	// Create leaderboard entry
 	entry := LeaderboardEntry{
+		ID:        gameEntryID(game.ID),
 		PlayerID:  submission.PlayerID,
-		Name:      submission.Name,
+		Name:      name,
 		Score:     submission.Score,
 		Duration:  submission.Duration,
 		Moves:     submission.Moves,
+		Mode:      mode,
+		MaxTile:   maxTile,
+		Won:       won,
 		Timestamp: time.Now(),
 	}
 
+	// Names on the deny list wait for an admin before they are shown
+	if nameNeedsReview(name) {
+		entry.Moderation = moderationPending
+		loggerFromContext(r.Context()).Info("name held for review", "name", name)
+	}
+
 	// Add to leaderboard
-	globalLeaderboard.AddScore(entry)
+	entry, err = globalLeaderboard.AddScore(r.Context(), entry)
+	if err != nil {
+		// Let the player try again
+		if err := deleteRecord(r.Context(), gameSubmittedKey(game.ID)); err != nil {
+			loggerFromContext(r.Context()).Error("failed to release game submission", "game_id", game.ID, "error", err)
+		}
+		loggerFromContext(r.Context()).Error("failed to accept score", "name", entry.Name, "error", err)
+		http.Error(w, "Leaderboard temporarily unavailable", http.StatusServiceUnavailable)
+		return
+	}
 
 	// Return the entry with generated ID
 	w.Header().Set("Content-Type", "application/json")
@@ -180,23 +472,33 @@ func leaderboardHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get limit from query parameter (default: 10)
-	limitStr := r.URL.Query().Get("limit")
-	limit := 10
-	if limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
+	// Pages are at most maxPageSize scores; follow nextCursor for the next one
+	page, ok := pageParams(w, r)
+	if !ok {
+		return
 	}
 
-	// Get top scores (will load fresh data from DynamoDB)
-	topScores := globalLeaderboard.GetTopScores(limit)
+	// Each partition (e.g. "classic", "fibonacci+time-attack") is ranked
+	// separately when mode is given. Time-attack games only appear on their
+	// own boards.
+	filter, ok := leaderboardFilterParams(w, r)
+	if !ok {
+		return
+	}
+
+	view, ok := leaderboardViewParam(w, r)
+	if !ok {
+		return
+	}
+
+	scores, err := globalLeaderboard.GetTopScores(r.Context(), filter, view, page)
+	if err != nil {
+		writeLeaderboardError(w, r, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"scores": topScores,
-		"total":  len(topScores),
-	})
+	json.NewEncoder(w).Encode(scores)
 }
 
 func playerRankHandler(w http.ResponseWriter, r *http.Request) {
@@ -211,16 +513,32 @@ func playerRankHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rank, entry := globalLeaderboard.GetPlayerRank(playerID)
-	if rank == -1 {
-		http.Error(w, "Player not found", http.StatusNotFound)
+	filter, ok := leaderboardFilterParams(w, r)
+	if !ok {
+		return
+	}
+
+	view, ok := leaderboardViewParam(w, r)
+	if !ok {
+		return
+	}
+
+	rank, entry, err := globalLeaderboard.GetPlayerRank(r.Context(), playerID, filter, view)
+	if err != nil {
+		writeLeaderboardError(w, r, err)
+		return
+	}
+	latest, err := globalLeaderboard.LatestScore(r.Context(), playerID, filter)
+	if err != nil {
+		writeLeaderboardError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"rank":  rank,
-		"entry": entry,
+		"rank":   rank,
+		"entry":  entry,
+		"latest": latest,
 	})
 }
 
@@ -230,7 +548,17 @@ func statsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats := globalLeaderboard.GetStats()
+	filter, ok := leaderboardFilterParams(w, r)
+	if !ok {
+		return
+	}
+
+	if filter, ok = statsRangeParams(w, r, filter); !ok {
+		return
+	}
+
+	// Computed from the whole leaderboard, as of the last refresh
+	stats := cachedStats(filter)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"2048game/engine"
+)
+
+// sessionItem is the sessions table item saveGameSession writes for game
+func sessionItem(t *testing.T, game *GameState) map[string]types.AttributeValue {
+	t.Helper()
+	data, err := json.Marshal(game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]types.AttributeValue{
+		"id":       &types.AttributeValueMemberS{Value: game.ID},
+		"gameData": &types.AttributeValueMemberS{Value: string(data)},
+	}
+}
+
+func TestSubmitScore(t *testing.T) {
+	over := newGameState(engine.Rules{})
+	over.PlayerID, over.Score, over.GameOver = "alice", 1200, true
+	playing := newGameState(engine.Rules{})
+	playing.PlayerID, playing.Score = "alice", 300
+	timed := newGameState(engine.Rules{})
+	timed.ID += "-timed"
+	timed.PlayerID, timed.Score = "alice", 800
+	timed.TimeAttack = newTimeAttackState(time.Now().Add(-2 * timeAttackLimit))
+	timed.TimeAttack.Moves = 42
+
+	useFakeDynamoDB(t, []map[string]types.AttributeValue{
+		sessionItem(t, over), sessionItem(t, playing), sessionItem(t, timed),
+	})
+	saved := globalLeaderboard
+	globalLeaderboard = &Leaderboard{}
+	defer func() { globalLeaderboard = saved }()
+
+	submit := func(body string) (*httptest.ResponseRecorder, LeaderboardEntry) {
+		rec := httptest.NewRecorder()
+		submitScoreHandler(rec, httptest.NewRequest(http.MethodPost, "/leaderboard/submit", strings.NewReader(body)))
+		var res struct {
+			Entry LeaderboardEntry `json:"entry"`
+		}
+		if rec.Code == http.StatusOK {
+			json.NewDecoder(rec.Body).Decode(&res)
+		}
+		return rec, res.Entry
+	}
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"no game", `{"playerId":"alice","name":"Alice","score":100}`, http.StatusBadRequest},
+		{"unknown game", `{"gameId":"nope","playerId":"alice","name":"Alice","score":100}`, http.StatusNotFound},
+		{"another player's game", `{"gameId":"` + over.ID + `","playerId":"bob","name":"Bob","score":100}`, http.StatusForbidden},
+		{"score above the game's", `{"gameId":"` + over.ID + `","playerId":"alice","name":"Alice","score":5000}`, http.StatusBadRequest},
+		{"game in progress", `{"gameId":"` + playing.ID + `","playerId":"alice","name":"Alice","score":300}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		if rec, _ := submit(tt.body); rec.Code != tt.code {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.code)
+		}
+	}
+
+	rec, entry := submit(`{"gameId":"` + over.ID + `","playerId":"alice","name":"Alice","score":1200}`)
+	if rec.Code != http.StatusOK || entry.ID != gameEntryID(over.ID) || entry.Mode != "classic" {
+		t.Fatalf("finished game: status %d, entry %+v", rec.Code, entry)
+	}
+	if rec, _ := submit(`{"gameId":"` + over.ID + `","playerId":"alice","name":"Alice","score":1200}`); rec.Code != http.StatusConflict {
+		t.Errorf("second submission: status %d, want 409", rec.Code)
+	}
+
+	// Time-attack results come from the server
+	rec, entry = submit(`{"gameId":"` + timed.ID + `","playerId":"alice","name":"Alice","score":1,"moves":1,"duration":1}`)
+	if rec.Code != http.StatusOK || entry.Score != 800 || entry.Moves != 42 || entry.Duration != int(timeAttackLimit.Seconds()) {
+		t.Errorf("time attack: status %d, entry %+v", rec.Code, entry)
+	}
+	if rec, _ := submit(`{"gameId":"` + timed.ID + `","playerId":"alice","name":"Alice","score":1}`); rec.Code != http.StatusConflict {
+		t.Errorf("second time-attack submission: status %d, want 409", rec.Code)
+	}
+
+	if n := len(globalLeaderboard.entries); n != 2 {
+		t.Errorf("leaderboard has %d entries, want 2", n)
+	}
+}
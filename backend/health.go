@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// DependencyStatus is the result of checking a single dependency
+type DependencyStatus struct {
+	Status    string    `json:"status"` // "ok" or "unavailable"
+	Detail    string    `json:"detail,omitempty"`
+	LatencyMs int64     `json:"latencyMs"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// readinessChecker runs dependency checks and caches the results so that
+// frequent probes don't hammer DynamoDB
+type readinessChecker struct {
+	mu        sync.Mutex
+	results   map[string]DependencyStatus
+	checkedAt time.Time
+	// refreshing is closed when the refresh in progress, if any, finishes
+	refreshing chan struct{}
+	ttl        time.Duration
+	timeout    time.Duration
+	checks     map[string]func(context.Context) (string, error)
+}
+
+var readiness = &readinessChecker{
+	ttl:     durationFromEnv("READINESS_CACHE_TTL", 5*time.Second),
+	timeout: durationFromEnv("READINESS_TIMEOUT", 2*time.Second),
+	checks: map[string]func(context.Context) (string, error){
+		"sessionStore":     checkSessionStore,
+		"leaderboardStore": checkLeaderboardStore,
+	},
+}
+
+// Check returns the cached dependency results, refreshing them if they are
+// stale. Only one refresh runs at a time, and the lock isn't held while it
+// does; concurrent probes wait for it instead.
+func (rc *readinessChecker) Check(ctx context.Context) (bool, map[string]DependencyStatus) {
+	rc.mu.Lock()
+	if rc.results == nil || time.Since(rc.checkedAt) > rc.ttl {
+		if rc.refreshing == nil {
+			done := make(chan struct{})
+			rc.refreshing = done
+			rc.mu.Unlock()
+
+			// Don't let a cancelled probe request poison the cached results
+			results := rc.runChecks(context.WithoutCancel(ctx))
+
+			rc.mu.Lock()
+			rc.results = results
+			rc.checkedAt = time.Now()
+			rc.refreshing = nil
+			close(done)
+		} else {
+			done := rc.refreshing
+			rc.mu.Unlock()
+			<-done
+			rc.mu.Lock()
+		}
+	}
+	defer rc.mu.Unlock()
+
+	ready := true
+	results := make(map[string]DependencyStatus, len(rc.results))
+	for name, status := range rc.results {
+		if status.Status != "ok" {
+			ready = false
+		}
+		results[name] = status
+	}
+	return ready, results
+}
+
+// runChecks runs every dependency check concurrently, each with its own timeout
+func (rc *readinessChecker) runChecks(ctx context.Context) map[string]DependencyStatus {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]DependencyStatus, len(rc.checks))
+
+	for name, check := range rc.checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) (string, error)) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, rc.timeout)
+			defer cancel()
+
+			start := time.Now()
+			detail, err := check(checkCtx)
+			status := DependencyStatus{
+				Status:    "ok",
+				Detail:    detail,
+				LatencyMs: time.Since(start).Milliseconds(),
+				CheckedAt: start,
+			}
+			if err != nil {
+				status.Status = "unavailable"
+				status.Detail = err.Error()
+			}
+
+			mu.Lock()
+			results[name] = status
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// checkSessionStore verifies the game sessions table is reachable; moves
+// cannot be served without it
+func checkSessionStore(ctx context.Context) (string, error) {
+	if dynamodbClient == nil {
+		return "", fmt.Errorf("DynamoDB client not initialized")
+	}
+	return describeTable(ctx, sessionsTableName())
+}
+
+// checkLeaderboardStore verifies the leaderboard table is reachable. Without
+// DynamoDB the leaderboard falls back to in-memory storage, which is still usable
+func checkLeaderboardStore(ctx context.Context) (string, error) {
+	if dynamodbClient == nil {
+		return "in-memory fallback", nil
+	}
+	return describeTable(ctx, leaderboardTableName())
+}
+
+func describeTable(ctx context.Context, tableName string) (string, error) {
+	out, err := dynamodbClient.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("table %s: %w", tableName, err)
+	}
+	return fmt.Sprintf("table %s is %s", tableName, out.Table.TableStatus), nil
+}
+
+// livezHandler reports whether the process is up; it never checks dependencies
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "alive"})
+}
+
+// readyzHandler reports whether the pod can serve traffic, with per-dependency detail
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ready, checks := readiness.Check(r.Context())
+
+	status := "ready"
+	code := http.StatusOK
+	if !ready {
+		status = "not ready"
+		code = http.StatusServiceUnavailable
+		loggerFromContext(r.Context()).Warn("readiness check failed", "checks", checks)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
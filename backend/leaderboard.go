@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"os"
 	"sort"
 	"sync"
@@ -10,13 +12,20 @@ import (
 )
 
 type LeaderboardEntry struct {
-	ID        string    `json:"id"`
-	PlayerID  string    `json:"playerId"`
-	Name      string    `json:"name"`
-	Score     int       `json:"score"`
-	Timestamp time.Time `json:"timestamp"`
-	Duration  int       `json:"duration"` // Game duration in seconds
-	Moves     int       `json:"moves"`    // Number of moves made
+	ID         string    `json:"id"`
+	PlayerID   string    `json:"playerId"`
+	Name       string    `json:"name"`
+	Score      int       `json:"score"`
+	Timestamp  time.Time `json:"timestamp"`
+	Duration   int       `json:"duration"` // Game duration in seconds
+	Moves      int       `json:"moves"`    // Number of moves made
+	Mode       string    `json:"mode"`     // Rule set the game was played with, e.g. "classic"
+	Ruleset    string    `json:"ruleset"`  // Mode without how it was played, e.g. "classic" for "time-attack"
+	BoardSize  int       `json:"boardSize"`
+	Partition  string    `json:"partition"`         // Mode and board size, e.g. "classic#4x4"
+	MaxTile    int       `json:"maxTile,omitempty"` // Only known for games submitted with their game ID
+	Won        bool      `json:"won,omitempty"`
+	Moderation string    `json:"moderation,omitempty"` // "pending" or "hidden" keeps the entry off public boards
 }
 
 type Leaderboard struct {
@@ -28,10 +37,10 @@ var globalLeaderboard = &Leaderboard{
 	entries: make([]LeaderboardEntry, 0),
 }
 
-// AddScore adds a new score to the leaderboard
-func (l *Leaderboard) AddScore(entry LeaderboardEntry) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
+// AddScore adds a new score to the leaderboard. When DynamoDB is configured the
+// entry is journaled and queued for writing; an error means it wasn't accepted.
+func (l *Leaderboard) AddScore(ctx context.Context, entry LeaderboardEntry) (LeaderboardEntry, error) {
+	logger := loggerFromContext(ctx)
 
 	// Generate ID if not provided
 	if entry.ID == "" {
@@ -42,107 +51,195 @@ func (l *Leaderboard) AddScore(entry LeaderboardEntry) {
 	if entry.Timestamp.IsZero() {
 		entry.Timestamp = time.Now()
 	}
+	entry.fillPartition()
 
-	l.entries = append(l.entries, entry)
+	// Queue the entry for DynamoDB before publishing it locally, so a rejected
+	// score never shows up on this pod only
+	if leaderboardQueue != nil {
+		if err := leaderboardQueue.Enqueue(ctx, entry); err != nil {
+			return entry, err
+		}
+	}
 
-	// Keep only top 1000 scores to prevent memory issues
-	if len(l.entries) > 1000 {
-		l.sortEntries()
-		l.entries = l.entries[:1000]
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A reload may have picked the entry up from the write queue already
+	if !l.hasEntry(entry.ID) {
+		l.entries = append(l.entries, entry)
 	}
 
-	// Save individual entry to DynamoDB immediately
-	if dynamodbClient != nil {
-		go func() {
-			if err := globalLeaderboard.saveEntryToDynamoDB(entry); err != nil {
-				log.Printf("Failed to save entry to DynamoDB: %v", err)
-			}
-		}()
+	// Keep only top 1000 scores to prevent memory issues
+	if len(l.entries) > 1000 {
+		l.trim(1000)
 	}
 
-	log.Printf("New score added: %s - %d points", entry.Name, entry.Score)
+	logger.Info("new score added", "entry_id", entry.ID, "name", entry.Name, "score", entry.Score)
+	return entry, nil
 }
 
-// GetTopScores returns the top N scores (always loads fresh from DynamoDB)
-func (l *Leaderboard) GetTopScores(limit int) []LeaderboardEntry {
-	// Always load fresh data from DynamoDB to ensure consistency across pods
-	l.loadFromPersistentStorage()
+// restoreEntry adds an already accepted entry (e.g. replayed from the write
+// journal) to the in-memory leaderboard if it isn't there yet
+func (l *Leaderboard) restoreEntry(entry LeaderboardEntry) {
+	entry.fillPartition()
 
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-	l.sortEntries()
+	if !l.hasEntry(entry.ID) {
+		l.entries = append(l.entries, entry)
+	}
+}
 
-	if limit > len(l.entries) {
-		limit = len(l.entries)
+// hasEntry reports whether the entry id is in the in-memory leaderboard. The
+// caller must hold l.mu.
+func (l *Leaderboard) hasEntry(id string) bool {
+	for _, existing := range l.entries {
+		if existing.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// setEntries replaces the in-memory leaderboard with entries loaded from
+// storage. Accepted entries still waiting in the write queue aren't in
+// storage yet, so they are kept.
+func (l *Leaderboard) setEntries(entries []LeaderboardEntry) {
+	if leaderboardQueue != nil {
+		loaded := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			loaded[entry.ID] = true
+		}
+		for _, entry := range leaderboardQueue.Pending() {
+			if !loaded[entry.ID] {
+				entries = append(entries, entry)
+			}
+		}
 	}
 
-	result := make([]LeaderboardEntry, limit)
-	copy(result, l.entries[:limit])
-	return result
+	l.mu.Lock()
+	l.entries = entries
+	l.mu.Unlock()
 }
 
-// GetPlayerRank returns the rank of a specific player
-func (l *Leaderboard) GetPlayerRank(playerID string) (int, *LeaderboardEntry) {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+// GetTopScores returns a page of the scores matching filter, every run or
+// each player's best (see viewBest). With DynamoDB every page is read from
+// the partition index (see leaderboardFilter.indexQuery); the whole
+// leaderboard is only loaded if that fails, or for a ruleset across modes.
+func (l *Leaderboard) GetTopScores(ctx context.Context, filter leaderboardFilter, view string, page pageRequest) (scorePage, error) {
+	if q, ok := filter.indexQuery(view); ok && dynamodbClient != nil {
+		result, err := queryLeaderboardPage(ctx, q, filter, page)
+		if err == nil || errors.Is(err, errPlayerNotRanked) {
+			return result, err
+		}
+		loggerFromContext(ctx).Warn("leaderboard index query failed, scanning instead", "index", q.Index, "partition", q.Value, "error", err)
+	}
+
+	// Load fresh data from DynamoDB to ensure consistency across pods
+	if err := l.loadFromPersistentStorage(ctx); err != nil {
+		return scorePage{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
 	l.sortEntries()
 
-	for i, entry := range l.entries {
-		if entry.PlayerID == playerID {
-			return i + 1, &entry
+	matching := make([]LeaderboardEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		if filter.matches(entry) {
+			matching = append(matching, entry)
 		}
 	}
+	if view == viewBest {
+		matching = bestPerPlayer(matching)
+	}
+	return paginate(matching, page)
+}
 
-	return -1, nil
+// modeName returns the entry's mode, treating entries from before modes
+// existed as classic
+func (e LeaderboardEntry) modeName() string {
+	if e.Mode == "" {
+		return "classic"
+	}
+	return e.Mode
 }
 
-// GetStats returns leaderboard statistics
-func (l *Leaderboard) GetStats() map[string]interface{} {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+// GetPlayerRank returns the rank of a player's best score among the scores
+// matching filter, ranking every run or each player's best, and the entry
+// ranked. It is the player's page of one score, read like any other page, so
+// every pod ranks the same way; errPlayerNotRanked means they have no score.
+func (l *Leaderboard) GetPlayerRank(ctx context.Context, playerID string, filter leaderboardFilter, view string) (int, LeaderboardEntry, error) {
+	page, err := l.GetTopScores(ctx, filter, view, pageRequest{Limit: 1, Around: playerID})
+	if err != nil {
+		return 0, LeaderboardEntry{}, err
+	}
+	return page.Offset + 1, page.Scores[0], nil
+}
 
-	if len(l.entries) == 0 {
-		return map[string]interface{}{
-			"totalPlayers": 0,
-			"totalGames":   0,
-			"highestScore": 0,
-			"averageScore": 0,
+// LatestScore returns the player's most recent score among the scores
+// matching filter, or nil. With DynamoDB it comes from the player best item
+// of filter's partition; a ruleset across modes, or a season the latest run
+// came after, is read from this pod's copy of the leaderboard instead.
+func (l *Leaderboard) LatestScore(ctx context.Context, playerID string, filter leaderboardFilter) (*LeaderboardEntry, error) {
+	if mode := filter.mode(); mode != "" && dynamodbClient != nil {
+		latest, err := loadPlayerLatest(ctx, partitionKey(mode, filter.BoardSize), playerID)
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil || filter.Period.contains(latest.Timestamp) {
+			return latest, nil
+		}
+		if latest.Timestamp.Before(filter.Period.From) {
+			return nil, nil
 		}
 	}
+	return l.localLatestScore(playerID, filter), nil
+}
 
-	l.sortEntries()
-
-	totalScore := 0
-	playerMap := make(map[string]bool)
+// localLatestScore is LatestScore from this pod's copy of the leaderboard
+func (l *Leaderboard) localLatestScore(playerID string, filter leaderboardFilter) *LeaderboardEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 
+	var latest *LeaderboardEntry
 	for _, entry := range l.entries {
-		totalScore += entry.Score
-		playerMap[entry.PlayerID] = true
-	}
-
-	return map[string]interface{}{
-		"totalPlayers": len(playerMap),
-		"totalGames":   len(l.entries),
-		"highestScore": l.entries[0].Score,
-		"averageScore": totalScore / len(l.entries),
+		if entry.PlayerID == playerID && filter.matches(entry) && (latest == nil || entry.Timestamp.After(latest.Timestamp)) {
+			e := entry
+			latest = &e
+		}
 	}
+	return latest
 }
 
-// sortEntries sorts entries by score (descending)
+// sortEntries sorts entries into leaderboard order (see entryLess)
 func (l *Leaderboard) sortEntries() {
 	sort.Slice(l.entries, func(i, j int) bool {
-		if l.entries[i].Score == l.entries[j].Score {
-			// If scores are equal, sort by timestamp (earlier is better)
-			return l.entries[i].Timestamp.Before(l.entries[j].Timestamp)
-		}
-		return l.entries[i].Score > l.entries[j].Score
+		return entryLess(l.entries[i], l.entries[j])
 	})
 }
 
+// trim keeps the best n entries. While seasons are enabled, the current
+// season's entries are kept ahead of older ones, which are archived anyway.
+func (l *Leaderboard) trim(n int) {
+	l.sortEntries()
+	if seasonCfg.enabled() {
+		now := time.Now()
+		start := seasonCfg.season(seasonCfg.seasonNumber(now), now).StartsAt
+		sort.SliceStable(l.entries, func(i, j int) bool {
+			return !l.entries[i].Timestamp.Before(start) && l.entries[j].Timestamp.Before(start)
+		})
+	}
+	l.entries = l.entries[:n]
+}
+
 // saveToPersistentStorage saves leaderboard to configured storage
-func (l *Leaderboard) saveToPersistentStorage() {
+func (l *Leaderboard) saveToPersistentStorage(ctx context.Context) {
+	ctx, span := startSpan(ctx, "leaderboard.save")
+	defer span.End()
+
 	// Try DynamoDB first (primary storage)
 	if dynamodbClient != nil {
 		l.saveToDynamoDB()
@@ -150,7 +247,7 @@ func (l *Leaderboard) saveToPersistentStorage() {
 
 	// Only use S3 if explicitly enabled and configured
 	if os.Getenv("S3_ENABLED") == "true" && s3Client != nil {
-		l.saveToS3()
+		l.saveToS3(ctx)
 	}
 
 	// JSON file as fallback
@@ -161,36 +258,58 @@ func (l *Leaderboard) saveToPersistentStorage() {
 func (l *Leaderboard) saveToJSON() {
 	_, err := json.MarshalIndent(l.entries, "", "  ")
 	if err != nil {
-		log.Printf("Error marshaling leaderboard: %v", err)
+		slog.Error("failed to marshal leaderboard", "error", err)
 		return
 	}
 
 	// In a real implementation, you'd write to a file
 	// For now, we'll just log that we would save
-	log.Printf("Would save %d entries to JSON storage", len(l.entries))
+	slog.Debug("would save leaderboard to JSON storage", "entries", len(l.entries))
 }
 
-// loadFromPersistentStorage loads leaderboard from configured storage
-func (l *Leaderboard) loadFromPersistentStorage() {
+// loadFromPersistentStorage loads leaderboard from configured storage. If
+// that fails, the in-memory leaderboard is left as it was and the error
+// returned.
+func (l *Leaderboard) loadFromPersistentStorage(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "leaderboard.load")
+	defer func() { endSpan(span, err) }()
+
 	// Try to load from primary storage (DynamoDB, then S3, then JSON)
 	if dynamodbClient != nil {
-		l.loadFromDynamoDB()
+		return l.loadFromDynamoDB(ctx)
 	} else if s3Client != nil {
-		l.loadFromS3()
-	} else {
-		l.loadFromJSON()
+		return l.loadFromS3(ctx)
 	}
+	l.loadFromJSON()
+	return nil
 }
 
 // loadFromJSON loads leaderboard from JSON file
 func (l *Leaderboard) loadFromJSON() {
 	// Implementation for loading from JSON file
-	log.Println("Loading leaderboard from JSON storage")
+	slog.Debug("loading leaderboard from JSON storage")
 }
 
 // Initialize leaderboard on startup
 func initLeaderboard() {
-	log.Println("Initializing leaderboard...")
-	globalLeaderboard.loadFromPersistentStorage()
-	log.Printf("Leaderboard initialized with %d entries", len(globalLeaderboard.entries))
+	slog.Info("initializing leaderboard")
+	if err := globalLeaderboard.loadFromPersistentStorage(context.Background()); err != nil {
+		slog.Error("failed to load leaderboard", "error", err)
+	}
+	slog.Info("leaderboard initialized", "entries", len(globalLeaderboard.entries))
+
+	// Older entries need partition attributes before the partition index can
+	// find them, and player best items built from them. Every pod tries; the
+	// updates are idempotent.
+	if dynamodbClient != nil {
+		go func() {
+			if err := backfillPartitions(context.Background()); err != nil {
+				slog.Error("failed to backfill leaderboard partitions", "error", err)
+				return
+			}
+			if err := backfillPlayerBests(context.Background()); err != nil {
+				slog.Error("failed to backfill player bests", "error", err)
+			}
+		}()
+	}
 }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"2048game/engine"
+)
+
+// Level is a predefined game setup, such as a layout of blockers and rocks.
+// A level with a goal is a puzzle: it starts from a fixed board, spawns a
+// fixed sequence of tiles and must be solved within a number of moves.
+// Levels are JSON or YAML files in LEVELS_DIR and are started with POST /game/new.
+type Level struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Rules     engine.Rules    `json:"rules"`
+	Board     *engine.Board   `json:"board,omitempty"`
+	Obstacles []LevelObstacle `json:"obstacles,omitempty"`
+
+	// Puzzle levels only
+	Goal      *PuzzleGoal    `json:"goal,omitempty"`
+	MoveLimit int            `json:"moveLimit,omitempty"`
+	Spawns    []engine.Spawn `json:"spawns,omitempty"`
+}
+
+// LevelObstacle places one blocker or rock on the starting board
+type LevelObstacle struct {
+	Row  int    `json:"row"`
+	Col  int    `json:"col"`
+	Type string `json:"type"`           // "blocker" or "rock"
+	Hits int    `json:"hits,omitempty"` // merges next to a rock needed to clear it
+}
+
+// maxMoveLimit bounds Level.MoveLimit
+const maxMoveLimit = 1000
+
+var levelIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// levels holds every loaded level by ID. It is filled once at startup.
+var levels = map[string]*Level{}
+
+// initLevels loads the level definitions from LEVELS_DIR (default "levels").
+// A broken level file stops the server rather than being skipped silently.
+func initLevels() {
+	dir := os.Getenv("LEVELS_DIR")
+	if dir == "" {
+		dir = "levels"
+	}
+
+	loaded, err := loadLevels(dir)
+	if err != nil {
+		slog.Error("failed to load levels", "dir", dir, "error", err)
+		os.Exit(1)
+	}
+	levels = loaded
+
+	slog.Info("levels loaded", "dir", dir, "levels", sortedLevelIDs())
+}
+
+// sortedLevelIDs returns the IDs of every loaded level in order
+func sortedLevelIDs() []string {
+	ids := make([]string, 0, len(levels))
+	for id := range levels {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// loadLevels reads every .json, .yaml and .yml file in dir. A missing
+// directory means no levels.
+func loadLevels(dir string) (map[string]*Level, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]*Level{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]*Level, len(entries))
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		level, err := readLevel(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if _, dup := loaded[level.ID]; dup {
+			return nil, fmt.Errorf("%s: duplicate level id %q", path, level.ID)
+		}
+		loaded[level.ID] = level
+	}
+	return loaded, nil
+}
+
+func readLevel(path string) (*Level, error) {
+	var level Level
+	if err := readDataFile(path, &level); err != nil {
+		return nil, err
+	}
+	if level.ID == "" {
+		level.ID = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := level.Validate(); err != nil {
+		return nil, err
+	}
+	return &level, nil
+}
+
+// readDataFile decodes the JSON or YAML file at path into v, rejecting
+// unknown fields
+func readDataFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// YAML is converted to JSON so both formats share one schema (and a
+	// level board's mix of numbers and obstacle objects decodes the same way)
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return err
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// IsPuzzle reports whether l has a goal to reach
+func (l *Level) IsPuzzle() bool {
+	return l.Goal != nil
+}
+
+// Validate reports whether l can be played
+func (l *Level) Validate() error {
+	if !levelIDPattern.MatchString(l.ID) {
+		return fmt.Errorf("invalid level id %q", l.ID)
+	}
+	if err := l.Rules.Validate(); err != nil {
+		return fmt.Errorf("invalid rules: %w", err)
+	}
+	board, err := l.StartBoard()
+	if err != nil {
+		return err
+	}
+
+	merge := l.Rules.MergeRule()
+	for r := 0; r < engine.Size; r++ {
+		for c := 0; c < engine.Size; c++ {
+			if t := board[r][c]; t > 0 && !merge.ValidTile(int(t)) {
+				return fmt.Errorf("tile %d at (%d,%d) is not a %s tile", t, r, c, l.Rules.Name())
+			}
+		}
+	}
+
+	if !l.IsPuzzle() {
+		if l.MoveLimit != 0 || len(l.Spawns) > 0 {
+			return fmt.Errorf("moveLimit and spawns need a goal")
+		}
+		if len(board.EmptyCells()) < 2 {
+			return fmt.Errorf("level needs at least two free cells for the starting tiles")
+		}
+		return nil
+	}
+
+	if l.Rules.Adversarial || len(l.Rules.SpawnWeights) > 0 {
+		return fmt.Errorf("puzzles spawn from their spawns list, so adversarial and spawnWeights can't be used")
+	}
+	if l.MoveLimit < 1 || l.MoveLimit > maxMoveLimit {
+		return fmt.Errorf("moveLimit must be between 1 and %d", maxMoveLimit)
+	}
+	if err := l.Goal.Validate(merge); err != nil {
+		return fmt.Errorf("invalid goal: %w", err)
+	}
+	for i, s := range l.Spawns {
+		if !merge.ValidTile(s.Value) {
+			return fmt.Errorf("spawn %d: %d is not a %s tile", i+1, s.Value, l.Rules.Name())
+		}
+		if s.Row < 0 || s.Row >= engine.Size || s.Col < 0 || s.Col >= engine.Size {
+			return fmt.Errorf("spawn %d: (%d,%d) is off the board", i+1, s.Row, s.Col)
+		}
+	}
+
+	game := engine.NewScripted(l.Rules, board, l.Spawns)
+	if l.Goal.Met(game) {
+		return fmt.Errorf("goal is already met on the starting board")
+	}
+	if len(game.Legal()) == 0 {
+		return fmt.Errorf("starting board has no legal moves")
+	}
+	return nil
+}
+
+// StartBoard returns the board the level starts from, before any tiles spawn
+func (l *Level) StartBoard() (engine.Board, error) {
+	var board engine.Board
+	if l.Board != nil {
+		board = *l.Board
+	}
+	for _, o := range l.Obstacles {
+		if o.Row < 0 || o.Row >= engine.Size || o.Col < 0 || o.Col >= engine.Size {
+			return board, fmt.Errorf("obstacle at (%d,%d) is off the board", o.Row, o.Col)
+		}
+		if board[o.Row][o.Col] != 0 {
+			return board, fmt.Errorf("obstacle at (%d,%d) overlaps another cell", o.Row, o.Col)
+		}
+		tile, err := engine.ParseObstacle(o.Type, o.Hits)
+		if err != nil {
+			return board, fmt.Errorf("obstacle at (%d,%d): %w", o.Row, o.Col, err)
+		}
+		board[o.Row][o.Col] = tile
+	}
+	return board, nil
+}
+
+// gameMode is the leaderboard mode for game: its rules, plus its level if it
+// was started from one and whether it was a time attack or a race
+func gameMode(game *GameState) string {
+	mode := modeName(game.Rules, game.Level)
+	var suffix string
+	switch {
+	case game.TimeAttack != nil:
+		suffix = timeAttackMode
+	case game.Race != nil:
+		suffix = "race"
+	default:
+		return mode
+	}
+	if mode == engine.VariantClassic {
+		return suffix
+	}
+	return mode + "+" + suffix
+}
+
+// Mode is the leaderboard mode of games started from l
+func (l *Level) Mode() string {
+	return modeName(l.Rules, l.ID)
+}
+
+func modeName(rules engine.Rules, levelID string) string {
+	mode := rules.Name()
+	if levelID == "" {
+		return mode
+	}
+	if rules.IsClassic() {
+		return "level-" + levelID
+	}
+	return mode + "+level-" + levelID
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey string
+
+const requestIDKey contextKey = "requestID"
+
+// requestIDHeader is read from incoming requests and echoed on every response
+const requestIDHeader = "X-Request-ID"
+
+// logLevel can be changed at runtime through /log/level
+var logLevel = new(slog.LevelVar)
+
+// initLogging configures the default slog logger based on ENVIRONMENT and LOG_LEVEL
+func initLogging() {
+	environment := os.Getenv("ENVIRONMENT")
+	if environment == "" {
+		environment = "development"
+	}
+
+	if lvl := os.Getenv("LOG_LEVEL"); lvl != "" {
+		if err := logLevel.UnmarshalText([]byte(lvl)); err != nil {
+			slog.Warn("invalid LOG_LEVEL, using info", "value", lvl)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+
+	var handler slog.Handler
+	if environment == "production" {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	logger := slog.New(handler).With("environment", environment)
+	slog.SetDefault(logger)
+}
+
+// generateRequestID returns a random 16 byte hex identifier
+func generateRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return generateID()
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestIDFromContext returns the request ID stored in ctx, if any
+func requestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// loggerFromContext returns the default logger annotated with the request ID
+// and the active trace ID, so log lines can be joined with traces
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := requestIDFromContext(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		logger = logger.With("trace_id", sc.TraceID().String())
+	}
+	return logger
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Flush lets streaming handlers (server-sent events) flush through the recorder
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// withRequestID assigns a request ID, echoes it in the response and logs the request
+func withRequestID(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = generateRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		h(rec, r.WithContext(ctx))
+
+		loggerFromContext(ctx).Info("request completed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	}
+}
+
+// logLevelHandler reports or changes the current log level. It is an admin
+// endpoint.
+func logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			Level string `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := logLevel.UnmarshalText([]byte(strings.TrimSpace(req.Level))); err != nil {
+			http.Error(w, "Invalid log level", http.StatusBadRequest)
+			return
+		}
+		loggerFromContext(r.Context()).Info("log level changed", "level", logLevel.Level().String())
+		audit(r, "log.level", logLevel.Level().String(), nil)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"level": logLevel.Level().String()})
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
@@ -14,25 +14,101 @@ import (
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
+	// Initialize structured logging before anything else logs
+	initLogging()
+
+	// Initialize tracing (exporter chosen by OTEL_TRACES_EXPORTER)
+	shutdownTracing := initTracing()
+
 	// Initialize storage backends
 	initStorage()
 
+	// Load level definitions; a broken level stops startup
+	initLevels()
+
+	// Load achievement definitions; a broken one stops startup too
+	initAchievements()
+
+	// Load the player name deny list
+	initModeration()
+
 	// Initialize leaderboard
 	initLeaderboard()
 
+	// Start archiving leaderboard seasons, if they are enabled
+	initSeasons()
+
+	// Start the leaderboard write queue, replaying any journaled scores
+	initWriteQueue()
+
+	// Compute leaderboard statistics, refreshed on a schedule
+	initStats()
+
 	// Game cleanup is now handled by DynamoDB TTL
 
+	// Health endpoints. Kubernetes probes are polled constantly, so they skip
+	// request logging; /health is kept for the ALB health check
+	http.HandleFunc("/health", route(healthHandler))
+	http.HandleFunc("/livez", livezHandler)
+	http.HandleFunc("/readyz", readyzHandler)
+
 	// Game endpoints
-	http.HandleFunc("/health", withCORS(healthHandler))
-	http.HandleFunc("/game/new", withCORS(newGameHandler))
-	http.HandleFunc("/game/move", withCORS(moveHandler))
-	http.HandleFunc("/game/state", withCORS(stateHandler))
+	http.HandleFunc("/game/new", route(newGameHandler))
+	http.HandleFunc("/game/move", route(moveHandler))
+	http.HandleFunc("/game/state", route(stateHandler))
+	http.HandleFunc("/game/levels", route(levelsHandler))
+
+	// Spectators
+	http.HandleFunc("/game/share", route(shareGameHandler))
+	http.HandleFunc("/game/watch", route(watchGameHandler))
+
+	// Race rooms
+	http.HandleFunc("/game/room/create", route(createRoomHandler))
+	http.HandleFunc("/game/room/join", route(joinRoomHandler))
+	http.HandleFunc("/game/room/leave", route(leaveRoomHandler))
+	http.HandleFunc("/game/room/ready", route(readyRoomHandler))
+	http.HandleFunc("/game/room/start", route(startRoomHandler))
+	http.HandleFunc("/game/room/state", route(roomStateHandler))
+	http.HandleFunc("/game/room/events", route(roomEventsHandler))
+	http.HandleFunc("/game/room/results", route(raceResultsHandler))
+
+	// Tournaments
+	http.HandleFunc("/game/tournaments", route(tournamentsHandler))
+	http.HandleFunc("/game/tournament/register", route(registerTournamentHandler))
+	http.HandleFunc("/game/tournament/play", route(playTournamentHandler))
+	http.HandleFunc("/game/tournament/state", route(tournamentStateHandler))
+	http.HandleFunc("/game/tournament/results", route(tournamentResultsHandler))
+
+	// Player endpoints
+	http.HandleFunc("/player/", route(playerHandler))
 
 	// Leaderboard endpoints
-	http.HandleFunc("/leaderboard/submit", withCORS(submitScoreHandler))
-	http.HandleFunc("/leaderboard/top", withCORS(leaderboardHandler))
-	http.HandleFunc("/leaderboard/rank", withCORS(playerRankHandler))
-	http.HandleFunc("/leaderboard/stats", withCORS(statsHandler))
+	http.HandleFunc("/leaderboard/submit", route(submitScoreHandler))
+	http.HandleFunc("/leaderboard/top", route(leaderboardHandler))
+	http.HandleFunc("/leaderboard/rank", route(playerRankHandler))
+	http.HandleFunc("/leaderboard/stats", route(statsHandler))
+	http.HandleFunc("/leaderboard/seasons", route(seasonsHandler))
+	http.HandleFunc("/leaderboard/seasons/podium", route(seasonPodiumHandler))
+
+	// Admin endpoints, only served when ADMIN_TOKEN is set
+	http.HandleFunc("/leaderboard/admin/tournaments/create", adminRoute(createTournamentHandler))
+	http.HandleFunc("/leaderboard/admin/tournaments/close", adminRoute(closeTournamentHandler))
+	http.HandleFunc("/leaderboard/admin/tournaments/participants/moderate", adminRoute(moderateParticipantHandler))
+	http.HandleFunc("/leaderboard/admin/review", adminRoute(reviewQueueHandler))
+	http.HandleFunc("/leaderboard/admin/entries/moderate", adminRoute(moderateEntryHandler))
+	http.HandleFunc("/leaderboard/admin/entries/delete", adminRoute(deleteEntryHandler))
+	http.HandleFunc("/leaderboard/admin/players/ban", adminRoute(banPlayerHandler))
+	http.HandleFunc("/leaderboard/admin/players/unban", adminRoute(unbanPlayerHandler))
+	http.HandleFunc("/leaderboard/admin/players/bans", adminRoute(playerBansHandler))
+	http.HandleFunc("/leaderboard/admin/sessions", adminRoute(sessionHandler))
+	http.HandleFunc("/leaderboard/admin/sessions/end", adminRoute(endSessionHandler))
+	http.HandleFunc("/leaderboard/admin/stats/refresh", adminRoute(refreshStatsHandler))
+	http.HandleFunc("/leaderboard/admin/reload", adminRoute(reloadLeaderboardHandler))
+	http.HandleFunc("/leaderboard/admin/audit", adminRoute(auditLogHandler))
+
+	// Operational endpoints, admin only: debug logging can flood the log
+	// pipeline and error logging can hide what is going on
+	http.HandleFunc("/log/level", adminRoute(logLevelHandler))
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -43,12 +119,14 @@ func main() {
 		Addr:    ":" + port,
 		Handler: nil,
 	}
+	server.RegisterOnShutdown(closeStreams)
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Server started on :%s", port)
+		slog.Info("server started", "port", port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+			slog.Error("server failed to start", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -56,17 +134,37 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
-
-	// Cleanup storage connections
-	cleanupStorage()
+	slog.Info("shutting down server")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Carry on if requests outlive ctx: the scores already accepted still need
+	// persisting and the buffered spans flushing
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		slog.Error("server forced to shutdown", "error", err)
+	}
+
+	// No new requests can arrive now; let accepted scores finish persisting.
+	// Anything still queued when ctx expires stays in the journal.
+	if leaderboardQueue != nil {
+		if err := leaderboardQueue.Drain(ctx); err != nil {
+			slog.Error("leaderboard write queue not fully drained", "error", err)
+		}
 	}
 
-	log.Println("Server exited")
+	// Cleanup storage connections
+	cleanupStorage()
+
+	// Flush any spans still buffered by the exporter
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+
+	slog.Info("server exited")
+}
+
+// route wraps a handler with the middleware shared by all public endpoints
+func route(h http.HandlerFunc) http.HandlerFunc {
+	return withRequestID(withTracing(withCORS(h)))
 }
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Player names are moderated before they reach a public board. A name is
+// normalised and must pass the length and character checks. A name that
+// matches the deny list is held for review: its entry stays hidden, and a
+// room or tournament shows heldName in its place, until an admin approves,
+// renames or hides it.
+
+const (
+	moderationPending = "pending" // waiting for review, not shown
+	moderationHidden  = "hidden"  // hidden by an admin
+)
+
+// heldName is shown for a room or tournament player whose name is held
+const heldName = "(name under review)"
+
+// denyWord is a deny list entry. It matches a word of a name with the same
+// skeleton, or, with prefix set (a trailing * in the list), any word whose
+// skeleton starts with it.
+type denyWord struct {
+	skeleton string
+	prefix   bool
+}
+
+// nameDenyList holds the denied words (NAME_DENY_LIST)
+var nameDenyList []denyWord
+
+var (
+	errEntryNotFound     = errors.New("leaderboard entry not found")
+	errInvalidModeration = errors.New("action must be approve, rename or hide")
+	errInvalidName       = errors.New("invalid name")
+)
+
+// nameExtraChars are allowed in names besides letters, digits and spaces
+const nameExtraChars = "-_.'"
+
+// nameLookalikes folds Cyrillic and Greek letters to the Latin letters they
+// look like, and leetspeak to the letters it stands for. Both names and deny
+// list words are folded, so "l" and "1" both become "i".
+var nameLookalikes = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'з': 'e', 'і': 'i', 'ї': 'i', 'ј': 'j', 'к': 'k',
+	'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x',
+	// Leetspeak
+	'0': 'o', '1': 'i', '!': 'i', '|': 'i', 'l': 'i', '3': 'e', '4': 'a', '@': 'a',
+	'5': 's', '$': 's', '7': 't', '+': 't', '8': 'b', '9': 'g',
+}
+
+// initModeration loads the deny list from NAME_DENY_LIST (default
+// "moderation/denylist.txt"): one word per line, with # comments. A missing
+// file means no words are denied.
+func initModeration() {
+	path := os.Getenv("NAME_DENY_LIST")
+	if path == "" {
+		path = "moderation/denylist.txt"
+	}
+
+	words, err := loadDenyList(path)
+	if err != nil {
+		slog.Error("failed to load name deny list", "path", path, "error", err)
+		os.Exit(1)
+	}
+	nameDenyList = words
+	slog.Info("name deny list loaded", "path", path, "words", len(words))
+}
+
+func loadDenyList(path string) ([]denyWord, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []denyWord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line, prefix := strings.CutSuffix(strings.TrimSpace(line), "*")
+		if word := nameSkeleton(line); word != "" {
+			words = append(words, denyWord{skeleton: word, prefix: prefix})
+		}
+	}
+	return words, scanner.Err()
+}
+
+// normalizeName returns name in the form it is stored and shown in: NFKC
+// normalised, so that fullwidth and other compatibility characters become
+// the plain ones, with runs of spaces collapsed. It fails if the result is
+// empty, longer than maxPlayerName characters, has characters other than
+// letters, digits, spaces and nameExtraChars, or mixes Latin, Cyrillic and
+// Greek letters, which is how lookalike names are usually made.
+func normalizeName(name string) (string, error) {
+	name = strings.Join(strings.Fields(norm.NFKC.String(name)), " ")
+	if name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+	if utf8.RuneCountInString(name) > maxPlayerName {
+		return "", fmt.Errorf("name can be at most %d characters", maxPlayerName)
+	}
+
+	scripts := make(map[string]bool)
+	for _, r := range name {
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			scripts["Latin"] = true
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["Cyrillic"] = true
+		case unicode.Is(unicode.Greek, r):
+			scripts["Greek"] = true
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r) && r != ' ' && !strings.ContainsRune(nameExtraChars, r) {
+			return "", fmt.Errorf("name can't contain %q", r)
+		}
+	}
+	if len(scripts) > 1 {
+		return "", fmt.Errorf("name can't mix Latin, Cyrillic and Greek letters")
+	}
+	return name, nil
+}
+
+// nameSkeleton reduces name to what it looks like: lower case, lookalikes
+// folded, accents, separators and repeated letters dropped, so "Ŝ.H-1-I-T"
+// and "shhit" both become "shit"
+func nameSkeleton(name string) string {
+	var b strings.Builder
+	var last rune
+	for _, r := range norm.NFKD.String(strings.ToLower(name)) {
+		if folded, ok := nameLookalikes[r]; ok {
+			r = folded
+		}
+		if !unicode.IsLetter(r) || r == last {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	return b.String()
+}
+
+// nameWords splits a normalised name into the words the deny list is matched
+// against: runs of letters and digits, with runs of single characters joined
+// so "S.H.I.T" is one word. A run with a lower case letter followed by an
+// upper case one is also split there, so "ShitLord" gives "ShitLord", "Shit"
+// and "Lord". The whole run is kept because case alone doesn't make a word:
+// "FuCk" would split into "Fu" and "Ck".
+func nameWords(name string) []string {
+	runs := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+
+	var words []string
+	for i, run := range runs {
+		if i > 0 && utf8.RuneCountInString(run) == 1 && utf8.RuneCountInString(runs[i-1]) == 1 {
+			words[len(words)-1] += run
+			continue
+		}
+		words = append(words, run)
+	}
+
+	for _, run := range words {
+		start, last := 0, rune(0)
+		for i, r := range run {
+			if unicode.IsUpper(r) && unicode.IsLower(last) {
+				words = append(words, run[start:i])
+				start = i
+			}
+			last = r
+		}
+		if start > 0 {
+			words = append(words, run[start:])
+		}
+	}
+	return words
+}
+
+// nameNeedsReview reports whether a word of a normalised name is on the deny
+// list. Words are matched whole, so "Hancock" and "Dickens" pass.
+func nameNeedsReview(name string) bool {
+	for _, word := range nameWords(name) {
+		skeleton := nameSkeleton(word)
+		for _, denied := range nameDenyList {
+			if skeleton == denied.skeleton || denied.prefix && strings.HasPrefix(skeleton, denied.skeleton) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shownName is how a room or tournament player's name is shown: heldName if
+// moderation holds it
+func shownName(name, moderation string) string {
+	if moderation != "" {
+		return heldName
+	}
+	return name
+}
+
+// visible reports whether e may be shown on public boards
+func (e LeaderboardEntry) visible() bool {
+	return e.Moderation == ""
+}
+
+// moderateName applies an admin's review action to a name, returning the name
+// and moderation status to save: "approve" shows it, "rename" shows newName
+// instead, and "hide" hides it
+func moderateName(name, action, newName string) (string, string, error) {
+	switch action {
+	case "approve":
+		return name, "", nil
+	case "rename":
+		normalized, err := normalizeName(newName)
+		if err != nil {
+			return "", "", fmt.Errorf("%w: %v", errInvalidName, err)
+		}
+		return normalized, "", nil
+	case "hide":
+		return name, moderationHidden, nil
+	default:
+		return "", "", errInvalidModeration
+	}
+}
+
+// Moderate applies an admin's review action to the entry id (see
+// moderateName)
+func (l *Leaderboard) Moderate(ctx context.Context, id, action, name string) (LeaderboardEntry, error) {
+	if err := l.loadFromPersistentStorage(ctx); err != nil {
+		return LeaderboardEntry{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entry *LeaderboardEntry
+	for i := range l.entries {
+		if l.entries[i].ID == id {
+			entry = &l.entries[i]
+			break
+		}
+	}
+	if entry == nil {
+		return LeaderboardEntry{}, errEntryNotFound
+	}
+
+	updated := *entry
+	var err error
+	if updated.Name, updated.Moderation, err = moderateName(updated.Name, action, name); err != nil {
+		return LeaderboardEntry{}, err
+	}
+
+	if dynamodbClient != nil {
+		if err := saveEntryModeration(ctx, updated, l.otherRuns(updated)); err != nil {
+			return LeaderboardEntry{}, err
+		}
+	}
+	*entry = updated
+	loggerFromContext(ctx).Info("leaderboard entry moderated", "entry_id", id, "action", action, "name", updated.Name)
+	return updated, nil
+}
+
+// ReviewQueue returns the entries waiting for review, oldest first
+func (l *Leaderboard) ReviewQueue(ctx context.Context) ([]LeaderboardEntry, error) {
+	if err := l.loadFromPersistentStorage(ctx); err != nil {
+		return nil, err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	queue := make([]LeaderboardEntry, 0)
+	for _, entry := range l.entries {
+		if entry.Moderation == moderationPending {
+			queue = append(queue, entry)
+		}
+	}
+	sort.Slice(queue, func(i, j int) bool { return queue[i].Timestamp.Before(queue[j].Timestamp) })
+	return queue, nil
+}
+
+// Moderation admin handlers
+
+// reviewQueueHandler lists the entries waiting for review
+func reviewQueueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	queue, err := globalLeaderboard.ReviewQueue(r.Context())
+	if err != nil {
+		loggerFromContext(r.Context()).Error("failed to load review queue", "error", err)
+		http.Error(w, "Failed to load review queue", http.StatusInternalServerError)
+		return
+	}
+	participants, err := heldParticipants(r.Context())
+	if err != nil {
+		loggerFromContext(r.Context()).Error("failed to load held tournament participants", "error", err)
+		http.Error(w, "Failed to load review queue", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries":      queue,
+		"total":        len(queue),
+		"participants": participants,
+	})
+}
+
+// moderateEntryHandler approves, renames or hides a leaderboard entry
+func moderateEntryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID     string `json:"id"`
+		Action string `json:"action"`
+		Name   string `json:"name"` // the new name, for rename
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := globalLeaderboard.Moderate(r.Context(), req.ID, req.Action, req.Name)
+	switch {
+	case errors.Is(err, errEntryNotFound):
+		http.Error(w, "Entry not found", http.StatusNotFound)
+		return
+	case errors.Is(err, errInvalidModeration), errors.Is(err, errInvalidName):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		loggerFromContext(r.Context()).Error("failed to moderate entry", "entry_id", req.ID, "error", err)
+		http.Error(w, "Failed to moderate entry", http.StatusInternalServerError)
+		return
+	}
+	audit(r, "entry."+req.Action, entry.ID, map[string]any{
+		"playerId": entry.PlayerID,
+		"name":     entry.Name,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
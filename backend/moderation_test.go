@@ -0,0 +1,134 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"Player1", "Player1", false},
+		{"  Jo   Smith ", "Jo Smith", false},
+		{"Ｐｌａｙｅｒ１", "Player1", false},
+		{"O'Brien-Jr.", "O'Brien-Jr.", false},
+		{"Ανδρέας", "Ανδρέας", false},
+		{"", "", true},
+		{"   ", "", true},
+		{strings.Repeat("a", maxPlayerName+1), "", true},
+		{"<script>", "", true},
+		{"Pаypal", "", true}, // Cyrillic а
+		{"Ηitler", "", true}, // Greek Η
+		{"hi​there", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeName(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNameSkeleton(t *testing.T) {
+	tests := map[string]string{
+		"shit":      "shit",
+		"Ŝ.H-1-I-T": "shit",
+		"shhit":     "shit",
+		"5H1T":      "shit",
+		"$h!t":      "shit",
+		"ѕнiт":      "shit",
+		"fυck":      "fuck",
+		"Nаzі":      "nazi",
+		"B1tch":     "bitch",
+		"Hancock":   "hancock",
+	}
+	for name, want := range tests {
+		if got := nameSkeleton(name); got != want {
+			t.Errorf("nameSkeleton(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNameWords(t *testing.T) {
+	tests := map[string][]string{
+		"Player One": {"Player", "One"},
+		"ShitLord":   {"ShitLord", "Shit", "Lord"},
+		"S.H.I.T":    {"SHIT"},
+		"S.H.I.T Jo": {"SHIT", "Jo"},
+		"fUCK":       {"fUCK", "f", "UCK"},
+		"FuCk":       {"FuCk", "Fu", "Ck"},
+		"x_y_zebra":  {"xy", "zebra"},
+	}
+	for name, want := range tests {
+		if got := nameWords(name); !reflect.DeepEqual(got, want) {
+			t.Errorf("nameWords(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNameNeedsReview(t *testing.T) {
+	words, err := loadDenyList("moderation/denylist.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := nameDenyList
+	nameDenyList = words
+	defer func() { nameDenyList = saved }()
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		// Plain and separated
+		{"fuck", true},
+		{"Fuck You", true},
+		{"S.H.I.T", true},
+		{"s h i t", true},
+		{"xX_bitch_Xx", true},
+		// Mixed case
+		{"fUCK", true},
+		{"FuCk", true},
+		{"bItch", true},
+		{"NaZi", true},
+		{"ShitLord", true},
+		{"BigDick", true},
+		// Leetspeak and repeats
+		{"5H1T", true},
+		{"fuuuck", true},
+		{"B1tch", true},
+		{"n4z1", true},
+		// Homoglyphs
+		{"СОСК", true},      // Cyrillic
+		{"сука Шит", false}, // Cyrillic words that aren't on the list
+		{"Ｆｕｃｋ", true},
+		{"Ŝhit", true},
+		// Prefix words
+		{"fucking", true},
+		{"Nazis", true},
+		// Whole words only
+		{"Hancock", false},
+		{"Dickens", false},
+		{"Cockburn", false},
+		{"Scunthorpe", false},
+		{"Therapist", false},
+		{"Player1", false},
+	}
+	for _, tt := range tests {
+		name, err := normalizeName(tt.name)
+		if err != nil {
+			t.Errorf("normalizeName(%q): %v", tt.name, err)
+			continue
+		}
+		if got := nameNeedsReview(name); got != tt.want {
+			t.Errorf("nameNeedsReview(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
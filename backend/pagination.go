@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// maxPageSize caps how many scores one leaderboard request returns
+// (LEADERBOARD_MAX_PAGE_SIZE)
+var maxPageSize = intFromEnv("LEADERBOARD_MAX_PAGE_SIZE", 100)
+
+var errPlayerNotRanked = errors.New("player has no score on this leaderboard")
+
+// entryLess is the leaderboard order: highest score first, then the earlier
+// score, then by ID so that no two entries tie and cursors stay stable
+func entryLess(a, b LeaderboardEntry) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.Before(b.Timestamp)
+	}
+	return a.ID < b.ID
+}
+
+// pageCursor marks the last entry of a page. The next page starts right after
+// it in leaderboard order, so scores arriving in between don't shift pages.
+type pageCursor struct {
+	Score     int       `json:"s"`
+	Timestamp time.Time `json:"t"`
+	ID        string    `json:"i"`
+	Offset    int       `json:"o,omitempty"` // scores ranked above the next page
+	// Where a DynamoDB index query for the next page starts, if it can skip
+	// what came before
+	Key *indexKey `json:"k,omitempty"`
+}
+
+// indexKey is the key of an item in a leaderboard index: the index partition,
+// the score and the item's ID
+type indexKey struct {
+	Partition string `json:"p"`
+	Score     int    `json:"s"`
+	ID        string `json:"i"`
+}
+
+// newPageCursor returns the cursor of the page after the one ending with e,
+// offset scores from the top
+func newPageCursor(e LeaderboardEntry, offset int, key *indexKey) string {
+	data, _ := json.Marshal(pageCursor{Score: e.Score, Timestamp: e.Timestamp, ID: e.ID, Offset: offset, Key: key})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func parsePageCursor(s string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// after reports whether e comes after the cursor
+func (c *pageCursor) after(e LeaderboardEntry) bool {
+	return entryLess(LeaderboardEntry{Score: c.Score, Timestamp: c.Timestamp, ID: c.ID}, e)
+}
+
+// pageRequest says which page of a leaderboard to return. At most one of
+// Cursor, Offset and Around is set.
+type pageRequest struct {
+	Limit  int
+	Offset int
+	Cursor *pageCursor
+	Around string // a player ID
+}
+
+// scorePage is one page of a leaderboard. The first score's rank is Offset+1.
+type scorePage struct {
+	Scores     []LeaderboardEntry `json:"scores"`
+	Total      int                `json:"total"` // scores on this page
+	Offset     int                `json:"offset"`
+	NextCursor string             `json:"nextCursor,omitempty"`
+}
+
+// paginate cuts the page p asks for out of entries, which must be in
+// leaderboard order
+func paginate(entries []LeaderboardEntry, p pageRequest) (scorePage, error) {
+	start := 0
+	switch {
+	case p.Cursor != nil:
+		start = sort.Search(len(entries), func(i int) bool { return p.Cursor.after(entries[i]) })
+	case p.Around != "":
+		found := playerIndex(entries, p.Around)
+		if found < 0 {
+			return scorePage{}, errPlayerNotRanked
+		}
+		// Centre the player's best score, keeping the page full near the end
+		start = max(0, min(found-p.Limit/2, len(entries)-p.Limit))
+	default:
+		start = min(p.Offset, len(entries))
+	}
+
+	end := min(start+p.Limit, len(entries))
+	page := scorePage{
+		Scores: append([]LeaderboardEntry{}, entries[start:end]...),
+		Total:  end - start,
+		Offset: start,
+	}
+	if end < len(entries) && end > start {
+		page.NextCursor = newPageCursor(entries[end-1], end, nil)
+	}
+	return page, nil
+}
+
+// playerIndex returns the index of the first of playerID's entries, or -1
+func playerIndex(entries []LeaderboardEntry, playerID string) int {
+	for i, entry := range entries {
+		if entry.PlayerID == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
+// pageParams reads the limit, offset, cursor and around query parameters,
+// writing an error response if they are invalid. limit defaults to 10 and is
+// capped at maxPageSize.
+func pageParams(w http.ResponseWriter, r *http.Request) (pageRequest, bool) {
+	q := r.URL.Query()
+	p := pageRequest{Limit: 10, Around: q.Get("around")}
+
+	if limitStr := q.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			p.Limit = min(parsedLimit, maxPageSize)
+		}
+	}
+
+	set := 0
+	if p.Around != "" {
+		set++
+	}
+	if offset := q.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return p, false
+		}
+		p.Offset = n
+		set++
+	}
+	if cursor := q.Get("cursor"); cursor != "" {
+		c, err := parsePageCursor(cursor)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return p, false
+		}
+		p.Cursor = c
+		set++
+	}
+	if set > 1 {
+		http.Error(w, "Use only one of cursor, offset and around", http.StatusBadRequest)
+		return p, false
+	}
+	return p, true
+}
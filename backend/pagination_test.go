@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// testEntries returns n random entries over a few modes and players, with
+// many tied scores and some hidden entries, and the items DynamoDB would
+// hold for them: the runs and each player's best item, with their latest run
+func testEntries(n int) ([]LeaderboardEntry, []map[string]types.AttributeValue) {
+	rng := rand.New(rand.NewSource(1))
+	modes := []string{"classic", "time-attack", "fibonacci", "race"}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	entries := make([]LeaderboardEntry, 0, n)
+	for i := 0; i < n; i++ {
+		e := LeaderboardEntry{
+			ID:        fmt.Sprintf("e%03d", i),
+			PlayerID:  fmt.Sprintf("p%d", rng.Intn(30)),
+			Score:     []int{100, 200, 200, 300, 400, 400, 400, 500}[rng.Intn(8)] + rng.Intn(3)*1000,
+			Timestamp: base.Add(time.Duration(rng.Intn(5)) * time.Hour),
+			Mode:      modes[rng.Intn(len(modes))],
+		}
+		if rng.Intn(10) == 0 {
+			e.Moderation = moderationHidden
+		}
+		e.fillPartition()
+		entries = append(entries, e)
+	}
+
+	latest := make(map[string]LeaderboardEntry)
+	for _, e := range entries {
+		if l, ok := latest[playerBestID(e)]; e.visible() && (!ok || e.Timestamp.After(l.Timestamp)) {
+			latest[playerBestID(e)] = e
+		}
+	}
+
+	sorted := append([]LeaderboardEntry{}, entries...)
+	sortLeaderboard(sorted)
+	var items []map[string]types.AttributeValue
+	best := make(map[string]bool)
+	for _, e := range sorted {
+		items = append(items, leaderboardItem(e))
+		if !e.visible() || best[playerBestID(e)] {
+			continue
+		}
+		best[playerBestID(e)] = true
+		item := leaderboardItem(e)
+		item["entryId"] = item["id"]
+		item["id"] = &types.AttributeValueMemberS{Value: playerBestID(e)}
+		item["partition"] = &types.AttributeValueMemberS{Value: bestPrefix + e.Partition}
+		l := latest[playerBestID(e)]
+		item["latestId"] = &types.AttributeValueMemberS{Value: l.ID}
+		item["latestScore"] = &types.AttributeValueMemberN{Value: strconv.Itoa(l.Score)}
+		item["latestAt"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(l.Timestamp.UnixMilli(), 10)}
+		items = append(items, item)
+	}
+	return entries, items
+}
+
+func sortLeaderboard(entries []LeaderboardEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entryLess(entries[i], entries[j]) })
+}
+
+// ranked returns the entries filter covers, in leaderboard order
+func ranked(entries []LeaderboardEntry, filter leaderboardFilter, view string) []LeaderboardEntry {
+	var want []LeaderboardEntry
+	for _, e := range entries {
+		if filter.matches(e) {
+			want = append(want, e)
+		}
+	}
+	sortLeaderboard(want)
+	if view == viewBest {
+		want = bestPerPlayer(want)
+	}
+	return want
+}
+
+func ids(entries []LeaderboardEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.ID
+	}
+	return out
+}
+
+func equalIDs(a, b []LeaderboardEntry) bool {
+	return fmt.Sprint(ids(a)) == fmt.Sprint(ids(b))
+}
+
+func TestPaginate(t *testing.T) {
+	entries, _ := testEntries(60)
+	sortLeaderboard(entries)
+
+	// Following cursors visits every entry once, even when a higher score
+	// arrives between pages
+	var got []LeaderboardEntry
+	above := 0 // entries added above the pages read so far
+	page := pageRequest{Limit: 7}
+	for {
+		res, err := paginate(entries, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Offset != len(got)+above {
+			t.Fatalf("page offset %d, want %d", res.Offset, len(got)+above)
+		}
+		got = append(got, res.Scores...)
+		if res.NextCursor == "" {
+			break
+		}
+		c, err := parsePageCursor(res.NextCursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page = pageRequest{Limit: 7, Cursor: c}
+		if len(got) == 14 {
+			top := LeaderboardEntry{ID: "new", Score: 1 << 20}
+			entries = append([]LeaderboardEntry{top}, entries...)
+			above++
+		}
+	}
+	if !equalIDs(got, entries[1:]) {
+		t.Errorf("cursor walk = %v, want %v", ids(got), ids(entries[1:]))
+	}
+
+	res, err := paginate(entries, pageRequest{Limit: 5, Offset: 10})
+	if err != nil || res.Offset != 10 || !equalIDs(res.Scores, entries[10:15]) {
+		t.Errorf("offset page = %v, %d, %v", ids(res.Scores), res.Offset, err)
+	}
+
+	player := entries[30].PlayerID
+	first := playerIndex(entries, player)
+	res, err = paginate(entries, pageRequest{Limit: 5, Around: player})
+	if err != nil || res.Offset != first-2 {
+		t.Errorf("around page offset = %d, %v; want %d", res.Offset, err, first-2)
+	}
+	res, err = paginate(entries, pageRequest{Limit: 5, Around: entries[0].PlayerID})
+	if err != nil || res.Offset != 0 {
+		t.Errorf("around the top player: offset %d, %v", res.Offset, err)
+	}
+	if _, err := paginate(entries, pageRequest{Limit: 5, Around: "nobody"}); !errors.Is(err, errPlayerNotRanked) {
+		t.Errorf("around an unranked player: %v, want errPlayerNotRanked", err)
+	}
+}
+
+func TestQueryLeaderboardPage(t *testing.T) {
+	entries, items := testEntries(300)
+	useFakeDynamoDB(t, items)
+	ctx := context.Background()
+
+	filters := []leaderboardFilter{
+		{BoardSize: 4},
+		{Mode: "fibonacci", BoardSize: 4},
+		{Mode: "time-attack", BoardSize: 4},
+		{BoardSize: 4, Period: seasonRange{From: time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)}},
+	}
+	for _, filter := range filters {
+		for _, view := range []string{viewAll, viewBest} {
+			q, ok := filter.indexQuery(view)
+			if !ok {
+				t.Fatalf("no index query for %+v", filter)
+			}
+			want := ranked(entries, filter, view)
+			for _, limit := range []int{1, 7, 25} {
+				name := fmt.Sprintf("%s %s limit %d", q.Value, view, limit)
+
+				var got []LeaderboardEntry
+				page := pageRequest{Limit: limit}
+				for {
+					res, err := queryLeaderboardPage(ctx, q, filter, page)
+					if err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					if res.Offset != len(got) {
+						t.Fatalf("%s: page offset %d, want %d", name, res.Offset, len(got))
+					}
+					got = append(got, res.Scores...)
+					if res.NextCursor == "" {
+						break
+					}
+					c, _ := parsePageCursor(res.NextCursor)
+					page = pageRequest{Limit: limit, Cursor: c}
+				}
+				if !equalIDs(got, want) {
+					t.Fatalf("%s: cursor walk = %v, want %v", name, ids(got), ids(want))
+				}
+
+				for _, offset := range []int{0, 5, len(want) - 2, len(want) + 3} {
+					res, err := queryLeaderboardPage(ctx, q, filter, pageRequest{Limit: limit, Offset: offset})
+					exp, _ := paginate(want, pageRequest{Limit: limit, Offset: offset})
+					if err != nil || res.Offset != exp.Offset || !equalIDs(res.Scores, exp.Scores) {
+						t.Errorf("%s offset %d: %v at %d, want %v at %d (%v)", name, offset, ids(res.Scores), res.Offset, ids(exp.Scores), exp.Offset, err)
+					}
+				}
+
+				for _, player := range []string{"p0", "p7", "p29", "nobody"} {
+					res, err := queryLeaderboardPage(ctx, q, filter, pageRequest{Limit: limit, Around: player})
+					exp, expErr := paginate(want, pageRequest{Limit: limit, Around: player})
+					if !errors.Is(err, expErr) || res.Offset != exp.Offset || !equalIDs(res.Scores, exp.Scores) {
+						t.Errorf("%s around %s: %v at %d (%v), want %v at %d (%v)", name, player, ids(res.Scores), res.Offset, err, ids(exp.Scores), exp.Offset, expErr)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGetPlayerRank(t *testing.T) {
+	entries, items := testEntries(300)
+	useFakeDynamoDB(t, items)
+
+	// A pod whose copy of the leaderboard is empty still ranks from the index
+	l := &Leaderboard{}
+	filter := leaderboardFilter{BoardSize: 4}
+	for _, view := range []string{viewAll, viewBest} {
+		want := ranked(entries, filter, view)
+		for _, player := range []string{"p0", "p3", "p29"} {
+			rank, entry, err := l.GetPlayerRank(context.Background(), player, filter, view)
+			i := playerIndex(want, player)
+			if err != nil || rank != i+1 || entry.ID != want[i].ID {
+				t.Errorf("%s rank of %s = %d, %s, %v; want %d, %s", view, player, rank, entry.ID, err, i+1, want[i].ID)
+			}
+		}
+		if _, _, err := l.GetPlayerRank(context.Background(), "nobody", filter, view); !errors.Is(err, errPlayerNotRanked) {
+			t.Errorf("%s rank of an unranked player: %v, want errPlayerNotRanked", view, err)
+		}
+	}
+}
+
+func TestLatestScore(t *testing.T) {
+	entries, items := testEntries(300)
+	useFakeDynamoDB(t, items)
+
+	// The pod's copy of the leaderboard is only used to work out the answer;
+	// with DynamoDB the latest run comes from the player best item
+	memory := &Leaderboard{entries: entries}
+	pod := &Leaderboard{}
+	for _, filter := range []leaderboardFilter{{BoardSize: 4}, {Mode: "fibonacci", BoardSize: 4}} {
+		for _, player := range []string{"p0", "p3", "p29", "nobody"} {
+			want := memory.localLatestScore(player, filter)
+			got, err := pod.LatestScore(context.Background(), player, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == nil) != (want == nil) || got != nil && (!got.Timestamp.Equal(want.Timestamp) || got.Mode != want.Mode) {
+				t.Errorf("latest of %s in %s = %+v, want %+v", player, filter.mode(), got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"2048game/engine"
+)
+
+// Leaderboard entries are partitioned by mode and board size. The mode is the
+// rule set plus how the game was played (a level, time attack or a race), so
+// a partition only ranks games that are comparable. The DynamoDB partition
+// index keeps each partition sorted by score.
+//
+// The default leaderboard, with no mode or ruleset picked, is the classic
+// partition of the board size.
+
+// partitionKey returns the partition of games with mode on a size×size board,
+// such as "classic#4x4" or "fibonacci+time-attack#4x4"
+func partitionKey(mode string, boardSize int) string {
+	return fmt.Sprintf("%s#%dx%d", mode, boardSize, boardSize)
+}
+
+// splitMode separates a mode into its rule set and how it was played, e.g.
+// "fibonacci+level-pillars+race" into "fibonacci" and "level-pillars+race"
+func splitMode(mode string) (ruleset, play string) {
+	var rules, plays []string
+	for _, part := range strings.Split(mode, "+") {
+		if part == timeAttackMode || part == "race" || strings.HasPrefix(part, "level-") {
+			plays = append(plays, part)
+		} else {
+			rules = append(rules, part)
+		}
+	}
+	ruleset = strings.Join(rules, "+")
+	if ruleset == "" {
+		ruleset = engine.VariantClassic
+	}
+	return ruleset, strings.Join(plays, "+")
+}
+
+// fillPartition sets the partition fields of entries saved before they
+// existed, or submitted without them
+func (e *LeaderboardEntry) fillPartition() {
+	if e.BoardSize == 0 {
+		e.BoardSize = engine.Size
+	}
+	if e.Ruleset == "" {
+		e.Ruleset, _ = splitMode(e.modeName())
+	}
+	if e.Partition == "" {
+		e.Partition = partitionKey(e.modeName(), e.BoardSize)
+	}
+}
+
+// leaderboardFilter selects the entries a leaderboard query covers
+type leaderboardFilter struct {
+	Mode      string // empty for classic, or every mode of Ruleset
+	Ruleset   string // empty for any
+	BoardSize int
+	Period    seasonRange
+}
+
+// leaderboardIndexQuery says which DynamoDB index query reads a leaderboard
+type leaderboardIndexQuery struct {
+	Index     string // name of the index
+	Attribute string // its partition key
+	Value     string
+	// Each player's best is picked from their runs while reading, so pages
+	// are read from the top of the index rather than resumed
+	BestPerPlayer bool
+}
+
+// mode returns the mode f covers, or "" if it covers every mode of a ruleset
+func (f leaderboardFilter) mode() string {
+	if f.Mode == "" && f.Ruleset == "" {
+		return engine.VariantClassic
+	}
+	return f.Mode
+}
+
+// indexQuery returns the index query that reads the leaderboard f covers in
+// view: its mode's partition, or its player best items for the all-time best
+// view. It reports false if f covers several partitions, which no index
+// serves.
+func (f leaderboardFilter) indexQuery(view string) (leaderboardIndexQuery, bool) {
+	mode := f.mode()
+	if mode == "" {
+		return leaderboardIndexQuery{}, false
+	}
+	q := leaderboardIndexQuery{
+		Index:     partitionIndexName(),
+		Attribute: "partition",
+		Value:     partitionKey(mode, f.BoardSize),
+	}
+	if view == viewBest {
+		// Player best items are all-time, so a season is ranked from its runs
+		if f.Period == (seasonRange{}) {
+			q.Value = bestPrefix + q.Value
+		} else {
+			q.BestPerPlayer = true
+		}
+	}
+	return q, true
+}
+
+func (f leaderboardFilter) matches(e LeaderboardEntry) bool {
+	if !e.visible() || !f.Period.contains(e.Timestamp) {
+		return false
+	}
+	if e.BoardSize != 0 && e.BoardSize != f.BoardSize {
+		return false
+	}
+	if f.Ruleset != "" {
+		if ruleset, _ := splitMode(e.modeName()); ruleset != f.Ruleset {
+			return false
+		}
+	}
+	if mode := f.mode(); mode != "" {
+		return e.modeName() == mode
+	}
+	return true
+}
+
+// leaderboardFilterParams reads the mode, ruleset, size and season query
+// parameters, writing an error response if any is invalid
+func leaderboardFilterParams(w http.ResponseWriter, r *http.Request) (leaderboardFilter, bool) {
+	q := r.URL.Query()
+	f := leaderboardFilter{
+		Mode:      q.Get("mode"),
+		Ruleset:   q.Get("ruleset"),
+		BoardSize: engine.Size,
+	}
+	if size := q.Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n != engine.Size {
+			http.Error(w, fmt.Sprintf("Invalid size: only %d is supported", engine.Size), http.StatusBadRequest)
+			return f, false
+		}
+		f.BoardSize = n
+	}
+
+	var err error
+	if f.Period, err = parseSeason(q.Get("season"), time.Now()); err != nil {
+		http.Error(w, "Invalid season: "+err.Error(), http.StatusBadRequest)
+		return f, false
+	}
+	return f, true
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaderboardFilterMatches(t *testing.T) {
+	entry := func(mode string) LeaderboardEntry {
+		e := LeaderboardEntry{Mode: mode}
+		e.fillPartition()
+		return e
+	}
+
+	tests := []struct {
+		name   string
+		filter leaderboardFilter
+		entry  LeaderboardEntry
+		want   bool
+	}{
+		{"default is classic", leaderboardFilter{}, entry("classic"), true},
+		{"default leaves out other rules", leaderboardFilter{}, entry("fibonacci"), false},
+		{"default leaves out levels", leaderboardFilter{}, entry("classic+level-pillars"), false},
+		{"default leaves out custom rules", leaderboardFilter{}, entry("custom"), false},
+		{"default leaves out time attack", leaderboardFilter{}, entry("time-attack"), false},
+		{"mode", leaderboardFilter{Mode: "fibonacci"}, entry("fibonacci"), true},
+		{"other mode", leaderboardFilter{Mode: "fibonacci"}, entry("classic"), false},
+		{"ruleset covers its modes", leaderboardFilter{Ruleset: "classic"}, entry("time-attack"), true},
+		{"ruleset leaves out other rules", leaderboardFilter{Ruleset: "classic"}, entry("fibonacci"), false},
+		{"hidden", leaderboardFilter{}, LeaderboardEntry{Mode: "classic", Moderation: "hidden"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.BoardSize = 4
+			if got := tt.filter.matches(tt.entry); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.entry.Mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaderboardFilterIndexQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter leaderboardFilter
+		view   string
+		want   leaderboardIndexQuery
+		ok     bool
+	}{
+		{"default", leaderboardFilter{}, viewAll, leaderboardIndexQuery{Value: "classic#4x4"}, true},
+		{"default best", leaderboardFilter{}, viewBest, leaderboardIndexQuery{Value: "best#classic#4x4"}, true},
+		{"mode", leaderboardFilter{Mode: "fibonacci"}, viewAll, leaderboardIndexQuery{Value: "fibonacci#4x4"}, true},
+		{"season best", leaderboardFilter{Period: seasonRange{From: time.Unix(0, 0)}}, viewBest, leaderboardIndexQuery{Value: "classic#4x4", BestPerPlayer: true}, true},
+		{"ruleset", leaderboardFilter{Ruleset: "classic"}, viewAll, leaderboardIndexQuery{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.BoardSize = 4
+			got, ok := tt.filter.indexQuery(tt.view)
+			if ok != tt.ok {
+				t.Fatalf("indexQuery ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if got.Attribute != "partition" || got.Value != tt.want.Value || got.BestPerPlayer != tt.want.BestPerPlayer {
+				t.Errorf("indexQuery = %+v, want partition %q, best per player %v", got, tt.want.Value, tt.want.BestPerPlayer)
+			}
+		})
+	}
+}
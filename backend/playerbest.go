@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Leaderboards come in two views: every run, or each player's best run only,
+// so one player can't fill the top of the board. With DynamoDB, each
+// player's best and most recent runs in a partition are kept in a player best
+// item, whose partition is the entry's prefixed with bestPrefix so that the
+// partition index ranks them too.
+
+const (
+	viewAll  = "all"
+	viewBest = "best"
+
+	bestPrefix = "best#"
+)
+
+// playerBestID is the ID of the player best item for entry's player and
+// partition
+func playerBestID(entry LeaderboardEntry) string {
+	return bestPrefix + entry.Partition + "#" + entry.PlayerID
+}
+
+// isPlayerBestID reports whether id belongs to a player best item rather than
+// a run
+func isPlayerBestID(id string) bool {
+	return strings.HasPrefix(id, bestPrefix)
+}
+
+// bestPerPlayer keeps the first entry of each player, which is their best if
+// entries are in leaderboard order. Entries without a player ID are kept.
+func bestPerPlayer(entries []LeaderboardEntry) []LeaderboardEntry {
+	seen := make(map[string]bool)
+	best := make([]LeaderboardEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.PlayerID != "" {
+			if seen[entry.PlayerID] {
+				continue
+			}
+			seen[entry.PlayerID] = true
+		}
+		best = append(best, entry)
+	}
+	return best
+}
+
+// leaderboardViewParam reads the view query parameter, "all" (the default) or
+// "best", writing an error response if it is neither
+func leaderboardViewParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	switch view := r.URL.Query().Get("view"); view {
+	case "", viewAll:
+		return viewAll, true
+	case viewBest:
+		return viewBest, true
+	default:
+		http.Error(w, `Invalid view: use "all" or "best"`, http.StatusBadRequest)
+		return "", false
+	}
+}
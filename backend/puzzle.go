@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"2048game/engine"
+)
+
+// PuzzleGoal is what a puzzle asks the player to reach
+type PuzzleGoal struct {
+	Type  string `json:"type"` // "tile", "score" or "clear"
+	Value int    `json:"value"`
+}
+
+// Validate reports whether g can be reached under merge
+func (g *PuzzleGoal) Validate(merge engine.MergeRule) error {
+	switch g.Type {
+	case "tile":
+		if !merge.ValidTile(g.Value) {
+			return fmt.Errorf("%d is not a valid tile", g.Value)
+		}
+	case "score", "clear":
+		if g.Value < 1 {
+			return fmt.Errorf("value must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown goal type %q (want tile, score or clear)", g.Type)
+	}
+	return nil
+}
+
+// Met reports whether game has reached g: a tile of at least Value, a score of
+// at least Value, or no tiles below Value left on the board
+func (g *PuzzleGoal) Met(game *engine.Game) bool {
+	switch g.Type {
+	case "tile":
+		return game.Board.MaxTile() >= g.Value
+	case "score":
+		return game.Score >= g.Value
+	case "clear":
+		for r := 0; r < engine.Size; r++ {
+			for c := 0; c < engine.Size; c++ {
+				if t := game.Board[r][c]; t > 0 && int(t) < g.Value {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Puzzle statuses
+const (
+	puzzlePlaying = "playing"
+	puzzleSolved  = "solved"
+	puzzleFailed  = "failed"
+)
+
+// PuzzleState tracks a puzzle game's progress towards its level's goal
+type PuzzleState struct {
+	Goal      PuzzleGoal `json:"goal"`
+	MoveLimit int        `json:"moveLimit"`
+	Moves     int        `json:"moves"`
+	Status    string     `json:"status"`
+}
+
+// afterMove counts a move of game and decides whether the puzzle is over. A
+// finished puzzle also ends the game, so no further moves are accepted.
+func (p *PuzzleState) afterMove(game *GameState) {
+	p.Moves++
+	switch {
+	case p.Goal.Met(&game.Game):
+		p.Status = puzzleSolved
+	case p.Moves >= p.MoveLimit || game.GameOver:
+		p.Status = puzzleFailed
+	default:
+		return
+	}
+	game.GameOver = true
+}
+
+// PuzzleResult is a player's best solution to one puzzle level
+type PuzzleResult struct {
+	Level    string    `json:"level"`
+	GameID   string    `json:"gameId"`
+	Moves    int       `json:"moves"`
+	Score    int       `json:"score"`
+	SolvedAt time.Time `json:"solvedAt"`
+}
+
+// betterThan reports whether r beats other: fewer moves, then a higher score
+func (r PuzzleResult) betterThan(other PuzzleResult) bool {
+	if r.Moves != other.Moves {
+		return r.Moves < other.Moves
+	}
+	return r.Score > other.Score
+}
+
+// puzzleResults keeps each player's best result per level, as one record
+// per player in the sessions table
+var puzzleResults puzzleResultStore
+
+type puzzleResultStore struct{}
+
+func puzzleResultKey(playerID string) string {
+	return "puzzle#" + playerID
+}
+
+// Best returns playerID's best results, keyed by level ID
+func (puzzleResultStore) Best(ctx context.Context, playerID string) (map[string]PuzzleResult, error) {
+	best := make(map[string]PuzzleResult)
+	if _, err := loadRecord(ctx, puzzleResultKey(playerID), &best); err != nil {
+		return nil, err
+	}
+	return best, nil
+}
+
+// errNotImproved leaves a player's record untouched in Record
+var errNotImproved = errors.New("not a personal best")
+
+// Record saves result for playerID if it beats their previous best for the
+// level. It reports whether it did.
+func (puzzleResultStore) Record(ctx context.Context, playerID string, result PuzzleResult) (bool, error) {
+	_, err := updateRecord(ctx, puzzleResultKey(playerID), 0, func(best *map[string]PuzzleResult, _ bool) error {
+		if *best == nil {
+			*best = make(map[string]PuzzleResult)
+		}
+		if prev, ok := (*best)[result.Level]; ok && !result.betterThan(prev) {
+			return errNotImproved
+		}
+		(*best)[result.Level] = result
+		return nil
+	})
+	if errors.Is(err, errNotImproved) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// recordPuzzleResult stores the result of a solved puzzle game for its
+// player. Failures are logged; the move itself has already been saved.
+func recordPuzzleResult(ctx context.Context, game *GameState) {
+	if game.Puzzle == nil || game.Puzzle.Status != puzzleSolved || game.PlayerID == "" {
+		return
+	}
+
+	logger := loggerFromContext(ctx)
+	result := PuzzleResult{
+		Level:    game.Level,
+		GameID:   game.ID,
+		Moves:    game.Puzzle.Moves,
+		Score:    game.Score,
+		SolvedAt: time.Now(),
+	}
+	improved, err := puzzleResults.Record(ctx, game.PlayerID, result)
+	if err != nil {
+		logger.Error("failed to record puzzle result", "game_id", game.ID, "level", game.Level, "error", err)
+		return
+	}
+	logger.Info("puzzle solved", "game_id", game.ID, "level", game.Level, "moves", result.Moves, "personal_best", improved)
+}
+
+// levelSummary describes a level in the level list
+type levelSummary struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Mode      string        `json:"mode"`
+	Puzzle    bool          `json:"puzzle"`
+	Goal      *PuzzleGoal   `json:"goal,omitempty"`
+	MoveLimit int           `json:"moveLimit,omitempty"`
+	Best      *PuzzleResult `json:"best,omitempty"`
+}
+
+// levelsHandler lists every level. With a playerId, puzzle levels include
+// that player's best result.
+func levelsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var best map[string]PuzzleResult
+	if playerID := r.URL.Query().Get("playerId"); playerID != "" {
+		var err error
+		if best, err = puzzleResults.Best(r.Context(), playerID); err != nil {
+			loggerFromContext(r.Context()).Error("failed to load puzzle results", "player_id", playerID, "error", err)
+			http.Error(w, "Failed to load puzzle results", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	summaries := make([]levelSummary, 0, len(levels))
+	for _, id := range sortedLevelIDs() {
+		level := levels[id]
+		summary := levelSummary{
+			ID:        level.ID,
+			Name:      level.Name,
+			Mode:      level.Mode(),
+			Puzzle:    level.IsPuzzle(),
+			Goal:      level.Goal,
+			MoveLimit: level.MoveLimit,
+		}
+		if result, ok := best[id]; ok {
+			summary.Best = &result
+		}
+		summaries = append(summaries, summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"levels": summaries,
+	})
+}
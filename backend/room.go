@@ -0,0 +1,684 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"2048game/engine"
+)
+
+// Room statuses
+const (
+	roomLobby    = "lobby"
+	roomRacing   = "racing"
+	roomFinished = "finished"
+)
+
+const (
+	minRacePlayers = 2
+	maxRacePlayers = 8
+	maxPlayerName  = 32
+)
+
+var (
+	// raceDuration is how long a race lasts once started (RACE_DURATION)
+	raceDuration = durationFromEnv("RACE_DURATION", 5*time.Minute)
+	// roomTTL is how long rooms are kept in the sessions table (ROOM_TTL)
+	roomTTL = durationFromEnv("ROOM_TTL", 2*time.Hour)
+	// roomPollInterval is how often room streams check storage for changes
+	// made on other pods (ROOM_POLL_INTERVAL)
+	roomPollInterval = durationFromEnv("ROOM_POLL_INTERVAL", time.Second)
+)
+
+// roomEvents wakes this pod's room streams as soon as a room changes here
+var roomEvents = newEventBroker()
+
+var (
+	errRoomNotFound = errors.New("room not found")
+	errRoomState    = errors.New("room is not in the right state")
+	errRoomFull     = errors.New("room is full")
+	errNotInRoom    = errors.New("not a member of this room")
+	errNotHost      = errors.New("only the host can do that")
+	errNotReady     = errors.New("not every player is ready")
+	errAlreadyIn    = errors.New("player is already in the room")
+)
+
+// Room is a lobby where two to eight players race on identical seeded boards.
+// It is stored in the sessions table and changed through updateRoom.
+type Room struct {
+	ID         string       `json:"id"`
+	HostID     string       `json:"hostId"`
+	Rules      engine.Rules `json:"rules"`
+	Seed       int64        `json:"seed,omitempty"` // kept secret until the race is over
+	Status     string       `json:"status"`
+	Players    []RoomPlayer `json:"players"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	StartedAt  *time.Time   `json:"startedAt,omitempty"`
+	Deadline   *time.Time   `json:"deadline,omitempty"`
+	FinishedAt *time.Time   `json:"finishedAt,omitempty"`
+	Results    []RaceResult `json:"results,omitempty"`
+	Version    int64        `json:"version"` // bumped on every change
+}
+
+// RoomPlayer is one player in a room and their live progress
+type RoomPlayer struct {
+	PlayerID   string `json:"playerId"`
+	Name       string `json:"name"`
+	Moderation string `json:"moderation,omitempty"` // "pending" shows heldName instead of Name
+	Token      string `json:"token,omitempty"`      // proves membership; only shown to its owner
+	GameID     string `json:"gameId,omitempty"`     // only shown to its owner
+	Ready      bool   `json:"ready"`
+	Score      int    `json:"score"`
+	MaxTile    int    `json:"maxTile"`
+	Moves      int    `json:"moves"`
+	Finished   bool   `json:"finished"`
+}
+
+// RaceResult is a player's final standing
+type RaceResult struct {
+	Rank     int    `json:"rank"`
+	PlayerID string `json:"playerId"`
+	Name     string `json:"name"`
+	Score    int    `json:"score"`
+	MaxTile  int    `json:"maxTile"`
+	Moves    int    `json:"moves"`
+	Finished bool   `json:"finished"` // false if the clock ran out first
+}
+
+// RaceInfo links a game session to the room it is racing in
+type RaceInfo struct {
+	RoomID string `json:"roomId"`
+}
+
+func roomKey(id string) string {
+	return "room#" + id
+}
+
+func raceResultKey(id string) string {
+	return "race#" + id
+}
+
+// generateRoomID returns a short code that is easy to share, avoiding
+// characters that are easily confused
+func generateRoomID() string {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, 6)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
+
+func generateSeed() int64 {
+	var b [8]byte
+	rand.Read(b[:])
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+// player returns the member holding token
+func (r *Room) player(token string) *RoomPlayer {
+	if token == "" {
+		return nil
+	}
+	for i := range r.Players {
+		if r.Players[i].Token == token {
+			return &r.Players[i]
+		}
+	}
+	return nil
+}
+
+// public returns a copy of r that is safe to show to anyone: no tokens, no
+// game IDs, no held names, and no seed while it could still help someone
+func (r Room) public() Room {
+	players := make([]RoomPlayer, len(r.Players))
+	for i, p := range r.Players {
+		p.Token = ""
+		p.GameID = ""
+		p.Name = shownName(p.Name, p.Moderation)
+		players[i] = p
+	}
+	r.Players = players
+	if r.Status != roomFinished {
+		r.Seed = 0
+	}
+	return r
+}
+
+// settle finishes a race once every player is done or the clock has run out
+func (r *Room) settle(now time.Time) {
+	if r.Status != roomRacing {
+		return
+	}
+	allDone := true
+	for _, p := range r.Players {
+		if !p.Finished {
+			allDone = false
+		}
+	}
+	if !allDone && now.Before(*r.Deadline) {
+		return
+	}
+
+	r.Status = roomFinished
+	r.FinishedAt = &now
+	r.Results = make([]RaceResult, 0, len(r.Players))
+	for _, p := range r.Players {
+		r.Results = append(r.Results, RaceResult{
+			PlayerID: p.PlayerID,
+			Name:     shownName(p.Name, p.Moderation),
+			Score:    p.Score,
+			MaxTile:  p.MaxTile,
+			Moves:    p.Moves,
+			Finished: p.Finished,
+		})
+	}
+	sort.SliceStable(r.Results, func(i, j int) bool {
+		a, b := r.Results[i], r.Results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.MaxTile != b.MaxTile {
+			return a.MaxTile > b.MaxTile
+		}
+		return a.Moves < b.Moves
+	})
+	for i := range r.Results {
+		r.Results[i].Rank = i + 1
+	}
+}
+
+// updateRoom applies change to room id, settles it and wakes its streams.
+// A race that finishes here also gets a permanent results record.
+func updateRoom(ctx context.Context, id string, change func(room *Room) error) (Room, error) {
+	wasFinished := false
+	room, err := updateRecord(ctx, roomKey(id), roomTTL, func(room *Room, exists bool) error {
+		if !exists {
+			return errRoomNotFound
+		}
+		wasFinished = room.Status == roomFinished
+		if err := change(room); err != nil {
+			return err
+		}
+		room.settle(time.Now())
+		room.Version++
+		return nil
+	})
+	if err != nil {
+		return room, err
+	}
+
+	roomEvents.Publish(id)
+	if !wasFinished && room.Status == roomFinished {
+		saveRaceResults(ctx, &room)
+	}
+	return room, nil
+}
+
+// saveRaceResults keeps the results of a finished race after the room expires
+func saveRaceResults(ctx context.Context, room *Room) {
+	record := map[string]interface{}{
+		"roomId":     room.ID,
+		"rules":      room.Rules,
+		"seed":       room.Seed,
+		"startedAt":  room.StartedAt,
+		"finishedAt": room.FinishedAt,
+		"results":    room.Results,
+	}
+	if err := saveRecord(ctx, raceResultKey(room.ID), record, 0); err != nil {
+		loggerFromContext(ctx).Error("failed to save race results", "room_id", room.ID, "error", err)
+		return
+	}
+	loggerFromContext(ctx).Info("race finished", "room_id", room.ID, "players", len(room.Players))
+}
+
+// loadRoom reads room id, settling a race whose clock has run out
+func loadRoom(ctx context.Context, id string) (Room, error) {
+	var room Room
+	found, err := loadRecord(ctx, roomKey(id), &room)
+	if err != nil {
+		return room, err
+	}
+	if !found {
+		return room, errRoomNotFound
+	}
+	if room.Status == roomRacing && !time.Now().Before(*room.Deadline) {
+		return updateRoom(ctx, id, func(*Room) error { return nil })
+	}
+	return room, nil
+}
+
+// newRaceGameState starts a player's game in room. Every player gets the same
+// starting tiles because they come from the room's seed.
+func newRaceGameState(room *Room, player *RoomPlayer) *GameState {
+	var empty engine.Board
+	return &GameState{
+		ID:        generateID(),
+		Game:      *engine.NewWithRules(engine.BoardSource(room.Seed, empty), room.Rules),
+		PlayerID:  player.PlayerID,
+		Race:      &RaceInfo{RoomID: room.ID},
+		CreatedAt: time.Now(),
+	}
+}
+
+// prepareRaceMove loads game's room before a move. It seeds the game's spawns
+// from the room, or ends the game if the race is over. It reports whether the
+// move can go ahead.
+func prepareRaceMove(ctx context.Context, game *GameState) (bool, error) {
+	room, err := loadRoom(ctx, game.Race.RoomID)
+	if err != nil {
+		return false, err
+	}
+	if room.Status != roomRacing {
+		game.GameOver = true
+		return false, nil
+	}
+	game.SetSource(engine.BoardSource(room.Seed, game.Board))
+	return true, nil
+}
+
+// reportRaceProgress publishes game's score and max tile to its room. A failure
+// is only logged: the move itself has been saved.
+func reportRaceProgress(ctx context.Context, game *GameState) {
+	_, err := updateRoom(ctx, game.Race.RoomID, func(room *Room) error {
+		if room.Status != roomRacing {
+			return nil
+		}
+		for i := range room.Players {
+			p := &room.Players[i]
+			if p.GameID == game.ID {
+				p.Score = game.Score
+				p.MaxTile = game.Board.MaxTile()
+				p.Moves++
+				p.Finished = game.GameOver
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		loggerFromContext(ctx).Error("failed to report race progress", "game_id", game.ID, "room_id", game.Race.RoomID, "error", err)
+	}
+}
+
+// Room handlers
+
+// roomRequest is the body of every room action
+type roomRequest struct {
+	RoomID   string       `json:"roomId"`
+	Token    string       `json:"token"`
+	PlayerID string       `json:"playerId"`
+	Name     string       `json:"name"`
+	Rules    engine.Rules `json:"rules"`
+	Ready    bool         `json:"ready"`
+
+	moderation string // set by validPlayer
+}
+
+// decodeRoomRequest reads a POST body, writing an error response if it can't
+func decodeRoomRequest(w http.ResponseWriter, r *http.Request) (*roomRequest, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	var req roomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	req.RoomID = strings.ToUpper(strings.TrimSpace(req.RoomID))
+	req.Name = strings.TrimSpace(req.Name)
+	return &req, true
+}
+
+// validPlayer checks the identity fields of a create or join request and
+// normalises the name. A name on the deny list is held: rooms don't last long
+// enough to review it, so the room shows heldName for the player throughout.
+func (req *roomRequest) validPlayer() bool {
+	name, err := normalizeName(req.Name)
+	if err != nil || req.PlayerID == "" {
+		return false
+	}
+	req.Name = name
+	if nameNeedsReview(name) {
+		req.moderation = moderationPending
+	}
+	return true
+}
+
+// writeRoomError maps room errors to HTTP responses
+func writeRoomError(w http.ResponseWriter, r *http.Request, roomID string, err error) {
+	switch {
+	case errors.Is(err, errRoomNotFound):
+		http.Error(w, "Room not found", http.StatusNotFound)
+	case errors.Is(err, errNotInRoom):
+		http.Error(w, "Not a member of this room", http.StatusForbidden)
+	case errors.Is(err, errNotHost):
+		http.Error(w, "Only the host can start the race", http.StatusForbidden)
+	case errors.Is(err, errRoomState), errors.Is(err, errRoomFull),
+		errors.Is(err, errNotReady), errors.Is(err, errAlreadyIn):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, context.DeadlineExceeded):
+		loggerFromContext(r.Context()).Error("room storage timed out", "room_id", roomID, "error", err)
+		http.Error(w, "Storage timeout", http.StatusGatewayTimeout)
+	default:
+		loggerFromContext(r.Context()).Error("room update failed", "room_id", roomID, "error", err)
+		http.Error(w, "Failed to update room", http.StatusInternalServerError)
+	}
+}
+
+// writeRoom responds with the public room, plus the caller's own membership
+// (including its token and game ID) when they are in it
+func writeRoom(w http.ResponseWriter, room Room, token string) {
+	resp := map[string]interface{}{"room": room.public()}
+	if me := room.player(token); me != nil {
+		resp["you"] = me
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// createRoomHandler opens a room with the caller as host
+func createRoomHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRoomRequest(w, r)
+	if !ok {
+		return
+	}
+	if !req.validPlayer() {
+		http.Error(w, "playerId and a name of up to 32 characters are required", http.StatusBadRequest)
+		return
+	}
+	if err := req.Rules.Validate(); err != nil {
+		http.Error(w, "Invalid rules: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	host := RoomPlayer{PlayerID: req.PlayerID, Name: req.Name, Moderation: req.moderation, Token: generateToken()}
+	room := Room{
+		ID:        generateRoomID(),
+		HostID:    req.PlayerID,
+		Rules:     req.Rules,
+		Status:    roomLobby,
+		Players:   []RoomPlayer{host},
+		CreatedAt: time.Now(),
+		Version:   1,
+	}
+
+	// Conditional create, in case the short room code is already taken
+	if _, err := updateRecord(r.Context(), roomKey(room.ID), roomTTL, func(rec *Room, exists bool) error {
+		if exists {
+			return errRoomState
+		}
+		*rec = room
+		return nil
+	}); err != nil {
+		writeRoomError(w, r, room.ID, err)
+		return
+	}
+
+	loggerFromContext(r.Context()).Info("room created", "room_id", room.ID, "host", req.PlayerID, "rules", room.Rules.Name())
+	writeRoom(w, room, host.Token)
+}
+
+// joinRoomHandler adds the caller to a room that hasn't started
+func joinRoomHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRoomRequest(w, r)
+	if !ok {
+		return
+	}
+	if !req.validPlayer() {
+		http.Error(w, "playerId and a name of up to 32 characters are required", http.StatusBadRequest)
+		return
+	}
+
+	token := generateToken()
+	room, err := updateRoom(r.Context(), req.RoomID, func(room *Room) error {
+		if room.Status != roomLobby {
+			return errRoomState
+		}
+		if len(room.Players) >= maxRacePlayers {
+			return errRoomFull
+		}
+		for _, p := range room.Players {
+			if p.PlayerID == req.PlayerID {
+				return errAlreadyIn
+			}
+		}
+		room.Players = append(room.Players, RoomPlayer{PlayerID: req.PlayerID, Name: req.Name, Moderation: req.moderation, Token: token})
+		return nil
+	})
+	if err != nil {
+		writeRoomError(w, r, req.RoomID, err)
+		return
+	}
+	writeRoom(w, room, token)
+}
+
+// leaveRoomHandler removes the caller from a room that hasn't started. If the
+// host leaves, the next player becomes host.
+func leaveRoomHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRoomRequest(w, r)
+	if !ok {
+		return
+	}
+
+	room, err := updateRoom(r.Context(), req.RoomID, func(room *Room) error {
+		if room.Status != roomLobby {
+			return errRoomState
+		}
+		me := room.player(req.Token)
+		if me == nil {
+			return errNotInRoom
+		}
+		leaving := me.PlayerID
+		players := room.Players[:0]
+		for _, p := range room.Players {
+			if p.PlayerID != leaving {
+				players = append(players, p)
+			}
+		}
+		room.Players = players
+		if room.HostID == leaving && len(players) > 0 {
+			room.HostID = players[0].PlayerID
+		}
+		return nil
+	})
+	if err != nil {
+		writeRoomError(w, r, req.RoomID, err)
+		return
+	}
+	writeRoom(w, room, "")
+}
+
+// readyRoomHandler marks the caller as ready (or not) to race
+func readyRoomHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRoomRequest(w, r)
+	if !ok {
+		return
+	}
+
+	room, err := updateRoom(r.Context(), req.RoomID, func(room *Room) error {
+		if room.Status != roomLobby {
+			return errRoomState
+		}
+		me := room.player(req.Token)
+		if me == nil {
+			return errNotInRoom
+		}
+		me.Ready = req.Ready
+		return nil
+	})
+	if err != nil {
+		writeRoomError(w, r, req.RoomID, err)
+		return
+	}
+	writeRoom(w, room, req.Token)
+}
+
+// startRoomHandler starts the race once every player is ready. Each player
+// gets a game session seeded from the room; they find its ID under "you".
+func startRoomHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRoomRequest(w, r)
+	if !ok {
+		return
+	}
+
+	room, err := updateRoom(r.Context(), req.RoomID, func(room *Room) error {
+		if room.Status != roomLobby {
+			return errRoomState
+		}
+		me := room.player(req.Token)
+		if me == nil {
+			return errNotInRoom
+		}
+		if me.PlayerID != room.HostID {
+			return errNotHost
+		}
+		if len(room.Players) < minRacePlayers {
+			return errNotReady
+		}
+		for _, p := range room.Players {
+			if !p.Ready {
+				return errNotReady
+			}
+		}
+
+		room.Seed = generateSeed()
+		// Sessions saved by an attempt that loses a write conflict are never
+		// referenced and simply expire
+		for i := range room.Players {
+			game := newRaceGameState(room, &room.Players[i])
+			if err := saveGameSession(r.Context(), game); err != nil {
+				return err
+			}
+			room.Players[i].GameID = game.ID
+			room.Players[i].MaxTile = game.Board.MaxTile()
+		}
+
+		now := time.Now()
+		deadline := now.Add(raceDuration)
+		room.Status = roomRacing
+		room.StartedAt = &now
+		room.Deadline = &deadline
+		return nil
+	})
+	if err != nil {
+		writeRoomError(w, r, req.RoomID, err)
+		return
+	}
+
+	loggerFromContext(r.Context()).Info("race started", "room_id", room.ID, "players", len(room.Players))
+	writeRoom(w, room, req.Token)
+}
+
+// roomStateHandler returns a room. With the caller's token it also includes
+// their own membership and game ID.
+func roomStateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.ToUpper(r.URL.Query().Get("id"))
+
+	room, err := loadRoom(r.Context(), id)
+	if err != nil {
+		writeRoomError(w, r, id, err)
+		return
+	}
+	writeRoom(w, room, r.URL.Query().Get("token"))
+}
+
+// roomEventsHandler streams the public room as a "room" event whenever it
+// changes, until the race is over or the client disconnects
+func roomEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.ToUpper(r.URL.Query().Get("id"))
+	ctx := r.Context()
+
+	room, err := loadRoom(ctx, id)
+	if err != nil {
+		writeRoomError(w, r, id, err)
+		return
+	}
+
+	wake, unsubscribe := roomEvents.Subscribe(id)
+	defer unsubscribe()
+
+	flusher, ok := startSSE(w)
+	if !ok {
+		return
+	}
+
+	poll := time.NewTicker(roomPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	sent := int64(-1)
+	for {
+		if room.Version != sent {
+			if err := writeSSE(w, flusher, "room", room.public()); err != nil {
+				return
+			}
+			sent = room.Version
+		}
+		if room.Status == roomFinished {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-serverClosing:
+			return
+		case <-heartbeat.C:
+			if err := writeSSEHeartbeat(w, flusher); err != nil {
+				return
+			}
+			continue
+		case <-wake:
+		case <-poll.C:
+		}
+
+		latest, err := loadRoom(ctx, id)
+		if err != nil {
+			if ctx.Err() == nil {
+				loggerFromContext(ctx).Warn("room stream failed to reload room", "room_id", id, "error", err)
+			}
+			return
+		}
+		room = latest
+	}
+}
+
+// raceResultsHandler returns the results record of a finished race
+func raceResultsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.ToUpper(r.URL.Query().Get("id"))
+
+	var record json.RawMessage
+	found, err := loadRecord(r.Context(), raceResultKey(id), &record)
+	if err != nil {
+		writeRoomError(w, r, id, err)
+		return
+	}
+	if !found {
+		http.Error(w, "Race results not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(record)
+}
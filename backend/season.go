@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"2048game/engine"
+)
+
+const (
+	// seasonArchiveSize is how many entries of each board are archived
+	seasonArchiveSize = 100
+	// seasonCheckInterval is how often pods look for seasons to archive
+	seasonCheckInterval = time.Minute
+	// maxListedSeasons caps GET /leaderboard/seasons
+	maxListedSeasons = 100
+)
+
+var errSeasonsDisabled = errors.New("seasons are not enabled")
+
+// seasonConfig splits the leaderboard into back-to-back seasons of Length,
+// the first starting at Start. Seasons are off when Length is zero.
+type seasonConfig struct {
+	Start      time.Time     // SEASON_START
+	Length     time.Duration // SEASON_LENGTH
+	ArchiveDir string        // SEASON_ARCHIVE_DIR, used when S3 isn't
+}
+
+var seasonCfg seasonConfig
+
+func (c seasonConfig) enabled() bool {
+	return c.Length > 0
+}
+
+// Season is one period of the leaderboard. Scores before the first season
+// belong to season 0.
+type Season struct {
+	Number   int       `json:"number"`
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+	Current  bool      `json:"current"`
+	Archived bool      `json:"archived"`
+}
+
+// seasonNumber returns the season t falls in
+func (c seasonConfig) seasonNumber(t time.Time) int {
+	if t.Before(c.Start) {
+		return 0
+	}
+	return int(t.Sub(c.Start)/c.Length) + 1
+}
+
+// season returns season n as of now
+func (c seasonConfig) season(n int, now time.Time) Season {
+	s := Season{
+		Number:   n,
+		StartsAt: c.Start.Add(time.Duration(n-1) * c.Length),
+		EndsAt:   c.Start.Add(time.Duration(n) * c.Length),
+		Current:  n == c.seasonNumber(now),
+	}
+	if n == 0 {
+		s.StartsAt = time.Time{}
+		s.EndsAt = c.Start
+	}
+	return s
+}
+
+// seasonRange limits leaderboard queries to a period. The zero value is all
+// time.
+type seasonRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (r seasonRange) contains(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) && (r.To.IsZero() || t.Before(r.To))
+}
+
+// parseSeason reads a season query parameter: a season number, "all", or
+// "current" (the default while seasons are enabled)
+func parseSeason(param string, now time.Time) (seasonRange, error) {
+	if param == "all" || (param == "" && !seasonCfg.enabled()) {
+		return seasonRange{}, nil
+	}
+	if !seasonCfg.enabled() {
+		return seasonRange{}, errSeasonsDisabled
+	}
+	n := seasonCfg.seasonNumber(now)
+	if param != "" && param != "current" {
+		var err error
+		if n, err = strconv.Atoi(param); err != nil || n < 0 || n > seasonCfg.seasonNumber(now) {
+			return seasonRange{}, fmt.Errorf("unknown season %q", param)
+		}
+	}
+	s := seasonCfg.season(n, now)
+	return seasonRange{From: s.StartsAt, To: s.EndsAt}, nil
+}
+
+// SeasonArchive is the final standings of a season, written once it is over
+type SeasonArchive struct {
+	Season     Season                        `json:"season"`
+	ArchivedAt time.Time                     `json:"archivedAt"`
+	TotalGames int                           `json:"totalGames"`
+	Overall    []LeaderboardEntry            `json:"overall"` // the default board: classic on the standard size
+	Modes      map[string][]LeaderboardEntry `json:"modes"`
+}
+
+// seasonStandings is the archived top of a board, sorted runs: each
+// player's best run, as in the best view, so one player can't take the
+// whole podium
+func seasonStandings(runs []LeaderboardEntry) []LeaderboardEntry {
+	best := bestPerPlayer(runs)
+	return best[:min(len(best), seasonArchiveSize)]
+}
+
+// seasonArchivedKey marks season n as archived to S3 in the sessions table,
+// so only one pod archives it
+func seasonArchivedKey(n int) string {
+	return "season#" + strconv.Itoa(n)
+}
+
+// seasonArchived reports whether season n has been archived. Local archives
+// are per pod, so every pod writes its own.
+func seasonArchived(ctx context.Context, n int) (bool, error) {
+	if !useS3() {
+		_, err := os.Stat(seasonArchivePath(n))
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	var done struct{}
+	return loadRecord(ctx, seasonArchivedKey(n), &done)
+}
+
+// initSeasons reads the season settings and, if seasons are enabled, starts
+// archiving each season as it ends
+func initSeasons() {
+	seasonCfg = seasonConfig{
+		Start:      time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Length:     durationFromEnv("SEASON_LENGTH", 0),
+		ArchiveDir: os.Getenv("SEASON_ARCHIVE_DIR"),
+	}
+	if seasonCfg.ArchiveDir == "" {
+		seasonCfg.ArchiveDir = "data/seasons"
+	}
+	if v := os.Getenv("SEASON_START"); v != "" {
+		start, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			slog.Error("invalid SEASON_START, expected RFC 3339", "value", v, "error", err)
+			os.Exit(1)
+		}
+		seasonCfg.Start = start
+	}
+	if !seasonCfg.enabled() {
+		slog.Info("seasons disabled")
+		return
+	}
+
+	current := seasonCfg.season(seasonCfg.seasonNumber(time.Now()), time.Now())
+	slog.Info("seasons enabled", "season", current.Number, "ends_at", current.EndsAt, "length", seasonCfg.Length)
+	go runSeasonRollover()
+}
+
+// runSeasonRollover archives the seasons that have ended (as far back as
+// seasons are listed), then keeps checking for the next one to end
+func runSeasonRollover() {
+	archivedThrough := max(seasonCfg.seasonNumber(time.Now())-maxListedSeasons, 0)
+	for {
+		if last := seasonCfg.seasonNumber(time.Now()) - 1; archivedThrough < last {
+			// Archiving from a partly loaded leaderboard would write wrong
+			// standings for good, so wait for the next check instead
+			ctx := context.Background()
+			if err := globalLeaderboard.loadFromPersistentStorage(ctx); err != nil {
+				slog.Error("failed to load leaderboard for season archive", "error", err)
+			} else {
+				for n := archivedThrough + 1; n <= last; n++ {
+					if err := archiveSeason(ctx, n); err != nil {
+						slog.Error("failed to archive season", "season", n, "error", err)
+						break
+					}
+					archivedThrough = n
+				}
+			}
+		}
+		time.Sleep(seasonCheckInterval)
+	}
+}
+
+// archiveSeason writes the final standings of season n from the loaded
+// leaderboard, unless that has been done already
+func archiveSeason(ctx context.Context, n int) error {
+	ctx, span := startSpan(ctx, "leaderboard.archiveSeason")
+	defer span.End()
+
+	if done, err := seasonArchived(ctx, n); err != nil || done {
+		return err
+	}
+
+	now := time.Now()
+	archive := SeasonArchive{
+		Season:     seasonCfg.season(n, now),
+		ArchivedAt: now,
+		Modes:      make(map[string][]LeaderboardEntry),
+	}
+	archive.Season.Archived = true
+	period := seasonRange{From: archive.Season.StartsAt, To: archive.Season.EndsAt}
+	overall := leaderboardFilter{BoardSize: engine.Size, Period: period}
+
+	var overallRuns []LeaderboardEntry
+	modeRuns := make(map[string][]LeaderboardEntry)
+	globalLeaderboard.mu.Lock()
+	globalLeaderboard.sortEntries()
+	for _, entry := range globalLeaderboard.entries {
+		if !entry.visible() || !period.contains(entry.Timestamp) {
+			continue
+		}
+		archive.TotalGames++
+		mode := entry.modeName()
+		modeRuns[mode] = append(modeRuns[mode], entry)
+		if overall.matches(entry) {
+			overallRuns = append(overallRuns, entry)
+		}
+	}
+	globalLeaderboard.mu.Unlock()
+
+	archive.Overall = seasonStandings(overallRuns)
+	for mode, runs := range modeRuns {
+		archive.Modes[mode] = seasonStandings(runs)
+	}
+
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return err
+	}
+	if useS3() {
+		if err := saveSeasonArchiveToS3(ctx, n, data); err != nil {
+			return err
+		}
+		// Two pods may both write the archive; it holds the same standings
+		if err := saveRecord(ctx, seasonArchivedKey(n), struct{}{}, 0); err != nil {
+			return err
+		}
+	} else {
+		// Written under a temporary name so a crash can't leave half an archive
+		if err := os.MkdirAll(seasonCfg.ArchiveDir, 0o755); err != nil {
+			return err
+		}
+		tmpPath := seasonArchivePath(n) + ".tmp"
+		if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpPath, seasonArchivePath(n)); err != nil {
+			return err
+		}
+	}
+	slog.Info("season archived", "season", n, "games", archive.TotalGames)
+	return nil
+}
+
+// loadSeasonArchive reads the archive of season n. It reports false if the
+// season hasn't been archived.
+func loadSeasonArchive(ctx context.Context, n int) (*SeasonArchive, bool, error) {
+	var data []byte
+	var err error
+	if useS3() {
+		data, err = loadSeasonArchiveFromS3(ctx, n)
+	} else {
+		data, err = os.ReadFile(seasonArchivePath(n))
+		if errors.Is(err, os.ErrNotExist) {
+			data, err = nil, nil
+		}
+	}
+	if err != nil || data == nil {
+		return nil, false, err
+	}
+
+	var archive SeasonArchive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, false, fmt.Errorf("failed to decode season archive: %w", err)
+	}
+	return &archive, true, nil
+}
+
+func seasonArchivePath(n int) string {
+	return filepath.Join(seasonCfg.ArchiveDir, fmt.Sprintf("season-%d.json", n))
+}
+
+// useS3 reports whether leaderboard data goes to S3
+func useS3() bool {
+	return os.Getenv("S3_ENABLED") == "true" && s3Client != nil && os.Getenv("S3_BUCKET") != ""
+}
+
+// Season handlers
+
+// seasonsHandler lists the seasons so far, newest first
+func seasonsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !seasonCfg.enabled() {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"enabled": false, "seasons": []Season{}})
+		return
+	}
+
+	now := time.Now()
+	current := seasonCfg.seasonNumber(now)
+	seasons := make([]Season, 0)
+	for n := current; n >= 1 && len(seasons) < maxListedSeasons; n-- {
+		s := seasonCfg.season(n, now)
+		if !s.Current {
+			found, err := seasonArchived(r.Context(), n)
+			if err != nil {
+				loggerFromContext(r.Context()).Error("failed to load season status", "season", n, "error", err)
+				http.Error(w, "Failed to load seasons", http.StatusInternalServerError)
+				return
+			}
+			s.Archived = found
+		}
+		seasons = append(seasons, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": true,
+		"current": seasonCfg.season(current, now),
+		"seasons": seasons,
+	})
+}
+
+// seasonPodiumHandler returns the top three of an archived season, overall or
+// for one mode
+func seasonPodiumHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !seasonCfg.enabled() {
+		http.Error(w, "Seasons are not enabled", http.StatusNotFound)
+		return
+	}
+
+	n, err := strconv.Atoi(r.URL.Query().Get("season"))
+	if err != nil || n < 0 {
+		http.Error(w, "season must be a season number", http.StatusBadRequest)
+		return
+	}
+	archive, found, err := loadSeasonArchive(r.Context(), n)
+	if err != nil {
+		loggerFromContext(r.Context()).Error("failed to load season archive", "season", n, "error", err)
+		http.Error(w, "Failed to load season", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Season not archived", http.StatusNotFound)
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	standings := archive.Overall
+	if mode != "" {
+		standings = archive.Modes[mode]
+	}
+	podium := make([]LeaderboardEntry, 0, 3)
+	for i := 0; i < len(standings) && i < 3; i++ {
+		podium = append(podium, standings[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"season":     archive.Season,
+		"mode":       mode,
+		"totalGames": archive.TotalGames,
+		"podium":     podium,
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSeasonNumber(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := seasonConfig{Start: start, Length: 7 * 24 * time.Hour}
+	tests := []struct {
+		t    time.Time
+		want int
+	}{
+		{start.Add(-time.Second), 0},
+		{start, 1},
+		{start.AddDate(0, 0, 7).Add(-time.Second), 1},
+		{start.AddDate(0, 0, 7), 2},
+		{start.AddDate(0, 0, 30), 5},
+	}
+	for _, tt := range tests {
+		if got := c.seasonNumber(tt.t); got != tt.want {
+			t.Errorf("seasonNumber(%v) = %d, want %d", tt.t, got, tt.want)
+		}
+	}
+
+	s := c.season(2, start)
+	if !s.StartsAt.Equal(start.AddDate(0, 0, 7)) || !s.EndsAt.Equal(start.AddDate(0, 0, 14)) || s.Current {
+		t.Errorf("season 2 = %+v", s)
+	}
+	if s := c.season(0, start); !s.StartsAt.IsZero() || !s.EndsAt.Equal(start) {
+		t.Errorf("season 0 = %+v", s)
+	}
+}
+
+func TestArchiveSeason(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	savedCfg, savedBoard := seasonCfg, globalLeaderboard
+	seasonCfg = seasonConfig{Start: start, Length: 24 * time.Hour, ArchiveDir: t.TempDir()}
+	defer func() { seasonCfg, globalLeaderboard = savedCfg, savedBoard }()
+
+	in := start.Add(time.Hour)
+	entries := []LeaderboardEntry{
+		{ID: "a1", PlayerID: "alice", Score: 900, Timestamp: in},
+		{ID: "a2", PlayerID: "alice", Score: 800, Timestamp: in},
+		{ID: "a3", PlayerID: "alice", Score: 700, Timestamp: in},
+		{ID: "b1", PlayerID: "bob", Score: 600, Timestamp: in},
+		{ID: "c1", PlayerID: "carol", Score: 500, Timestamp: in},
+		{ID: "d1", PlayerID: "dave", Score: 400, Timestamp: in},
+		{ID: "t1", PlayerID: "bob", Score: 5000, Timestamp: in, Mode: "time-attack"},
+		{ID: "f1", PlayerID: "carol", Score: 4000, Timestamp: in, Mode: "fibonacci"},
+		{ID: "big", PlayerID: "erin", Score: 9000, Timestamp: in, BoardSize: 5},
+		{ID: "hidden", PlayerID: "mallory", Score: 9999, Timestamp: in, Moderation: moderationHidden},
+		{ID: "later", PlayerID: "frank", Score: 9999, Timestamp: start.AddDate(0, 0, 1)},
+	}
+	for i := range entries {
+		entries[i].fillPartition()
+	}
+	globalLeaderboard = &Leaderboard{entries: entries}
+
+	ctx := context.Background()
+	if err := archiveSeason(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	archive, found, err := loadSeasonArchive(ctx, 1)
+	if err != nil || !found {
+		t.Fatalf("loadSeasonArchive: %v, %v", found, err)
+	}
+	if archive.TotalGames != 9 {
+		t.Errorf("total games = %d, want 9", archive.TotalGames)
+	}
+	// One run per player, and only classic on the standard board overall
+	if got := ids(archive.Overall); !equalIDs(archive.Overall, []LeaderboardEntry{{ID: "a1"}, {ID: "b1"}, {ID: "c1"}, {ID: "d1"}}) {
+		t.Errorf("overall = %v, want [a1 b1 c1 d1]", got)
+	}
+	if got := archive.Modes["time-attack"]; !equalIDs(got, []LeaderboardEntry{{ID: "t1"}}) {
+		t.Errorf("time-attack = %v, want [t1]", ids(got))
+	}
+
+	// Archiving again leaves the archive alone
+	globalLeaderboard = &Leaderboard{}
+	if err := archiveSeason(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if again, _, _ := loadSeasonArchive(ctx, 1); len(again.Overall) != len(archive.Overall) {
+		t.Errorf("second archive rewrote season 1: %v", ids(again.Overall))
+	}
+
+	rec := httptest.NewRecorder()
+	seasonPodiumHandler(rec, httptest.NewRequest(http.MethodGet, "/leaderboard/seasons/podium?season=1", nil))
+	var res struct {
+		Podium []LeaderboardEntry `json:"podium"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatalf("podium response %d: %v", rec.Code, err)
+	}
+	if !equalIDs(res.Podium, []LeaderboardEntry{{ID: "a1"}, {ID: "b1"}, {ID: "c1"}}) {
+		t.Errorf("podium = %v, want [a1 b1 c1]", ids(res.Podium))
+	}
+}
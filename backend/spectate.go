@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"2048game/engine"
+)
+
+var (
+	// spectatorPollInterval is how often spectator streams check storage for
+	// moves handled by other pods (SPECTATOR_POLL_INTERVAL)
+	spectatorPollInterval = durationFromEnv("SPECTATOR_POLL_INTERVAL", time.Second)
+	// shareTTL is how long a share token keeps working (SHARE_TTL)
+	shareTTL = durationFromEnv("SHARE_TTL", 24*time.Hour)
+)
+
+// spectatorPresenceTTL is how long a spectator counts without a heartbeat
+const spectatorPresenceTTL = 3 * sseHeartbeat
+
+// spectatorEvents wakes this pod's spectator streams when a watched game moves
+var spectatorEvents = newEventBroker()
+
+// latestGames holds the newest state of games watched from this pod, so
+// local spectators see a move without waiting for storage
+var latestGames = struct {
+	sync.Mutex
+	games map[string]*GameState
+}{games: make(map[string]*GameState)}
+
+// spectatorView is what spectators see of a game: never its ID or share
+// token, since the game ID is what allows moves
+type spectatorView struct {
+	engine.Game
+	Level      string             `json:"level,omitempty"`
+	Puzzle     *PuzzleState       `json:"puzzle,omitempty"`
+	TimeAttack *TimeAttackState   `json:"timeAttack,omitempty"`
+	Moves      int                `json:"moves"`
+	LastMove   *engine.MoveResult `json:"lastMove,omitempty"`
+	Spectators int                `json:"spectators"`
+}
+
+func newSpectatorView(game *GameState, spectators int) spectatorView {
+	return spectatorView{
+		Game:       game.Game,
+		Level:      game.Level,
+		Puzzle:     game.Puzzle,
+		TimeAttack: game.TimeAttack,
+		Moves:      game.Moves,
+		LastMove:   game.LastMove,
+		Spectators: spectators,
+	}
+}
+
+// shareRecord maps a share token to the game it watches
+type shareRecord struct {
+	GameID string `json:"gameId"`
+}
+
+func shareKey(token string) string {
+	return "share#" + token
+}
+
+func spectatorsKey(gameID string) string {
+	return "spectators#" + gameID
+}
+
+// publishToSpectators hands game's new state to this pod's spectators
+func publishToSpectators(game *GameState) {
+	if game.ShareToken == "" || spectatorEvents.Count(game.ID) == 0 {
+		return
+	}
+	latestGames.Lock()
+	latestGames.games[game.ID] = cloneGameState(game)
+	latestGames.Unlock()
+	spectatorEvents.Publish(game.ID)
+}
+
+// cloneGameState copies game deeply enough that each spectator stream can
+// update its clock without touching the handler's or other streams' copies
+func cloneGameState(game *GameState) *GameState {
+	clone := *game
+	if game.Puzzle != nil {
+		puzzle := *game.Puzzle
+		clone.Puzzle = &puzzle
+	}
+	if game.TimeAttack != nil {
+		timeAttack := *game.TimeAttack
+		clone.TimeAttack = &timeAttack
+	}
+	return &clone
+}
+
+// setSpectatorPresence adds or removes a spectator of gameID and returns how
+// many are watching. Presence expires unless refreshed, so spectators on a
+// pod that dies stop being counted.
+func setSpectatorPresence(ctx context.Context, gameID, watcherID string, present bool) (int, error) {
+	watchers, err := updateRecord(ctx, spectatorsKey(gameID), time.Hour, func(watchers *map[string]time.Time, _ bool) error {
+		if *watchers == nil {
+			*watchers = make(map[string]time.Time)
+		}
+		now := time.Now()
+		for id, expires := range *watchers {
+			if now.After(expires) {
+				delete(*watchers, id)
+			}
+		}
+		if present {
+			(*watchers)[watcherID] = now.Add(spectatorPresenceTTL)
+		} else {
+			delete(*watchers, watcherID)
+		}
+		return nil
+	})
+	return len(watchers), err
+}
+
+// countSpectators returns how many spectators are watching gameID. Errors
+// count as nobody watching; the number is only informational.
+func countSpectators(ctx context.Context, gameID string) int {
+	var watchers map[string]time.Time
+	if _, err := loadRecord(ctx, spectatorsKey(gameID), &watchers); err != nil {
+		loggerFromContext(ctx).Debug("failed to count spectators", "game_id", gameID, "error", err)
+		return 0
+	}
+	count := 0
+	now := time.Now()
+	for _, expires := range watchers {
+		if now.Before(expires) {
+			count++
+		}
+	}
+	return count
+}
+
+// expireForSpectator ends a time-attack game whose clock ran out while the
+// player was idle, and refreshes its remaining time. Only the spectator's copy
+// changes; the player's next request records the expiry.
+func expireForSpectator(game *GameState) {
+	if game.TimeAttack == nil {
+		return
+	}
+	now := time.Now()
+	game.TimeAttack.expire(game, now)
+	game.TimeAttack.tick(now)
+}
+
+// shareGameHandler returns a share token for a game, creating it on first use.
+// Spectators watch with the token; it can't be used to make moves.
+func shareGameHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	game, err := loadGameSession(r.Context(), req.ID)
+	if err != nil {
+		writeSessionLoadError(w, r, req.ID, err)
+		return
+	}
+
+	if game.ShareToken == "" {
+		token := generateToken()
+		if err := saveRecord(r.Context(), shareKey(token), shareRecord{GameID: game.ID}, shareTTL); err != nil {
+			loggerFromContext(r.Context()).Error("failed to save share token", "game_id", game.ID, "error", err)
+			http.Error(w, "Failed to share game", http.StatusInternalServerError)
+			return
+		}
+		game.ShareToken = token
+		if err := saveGameSession(r.Context(), game); err != nil {
+			loggerFromContext(r.Context()).Error("failed to save game session", "game_id", game.ID, "error", err)
+			http.Error(w, "Failed to share game", http.StatusInternalServerError)
+			return
+		}
+		loggerFromContext(r.Context()).Info("game shared", "game_id", game.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"shareToken": game.ShareToken})
+}
+
+// watchGameHandler streams a shared game to a spectator. It sends a "state"
+// event with the whole game on connect and after every change, preceded by a
+// "move" event with the merges and spawns when a single move was made.
+func watchGameHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	logger := loggerFromContext(ctx)
+
+	var share shareRecord
+	found, err := loadRecord(ctx, shareKey(r.URL.Query().Get("token")), &share)
+	if err != nil {
+		logger.Error("failed to load share token", "error", err)
+		http.Error(w, "Failed to load game", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Unknown share token", http.StatusNotFound)
+		return
+	}
+	gameID := share.GameID
+
+	game, err := loadGameSession(ctx, gameID)
+	if err != nil {
+		writeSessionLoadError(w, r, gameID, err)
+		return
+	}
+
+	wake, unsubscribe := spectatorEvents.Subscribe(gameID)
+	defer func() {
+		unsubscribe()
+		if spectatorEvents.Count(gameID) == 0 {
+			latestGames.Lock()
+			delete(latestGames.games, gameID)
+			latestGames.Unlock()
+		}
+	}()
+
+	watcherID := generateToken()
+	spectators, err := setSpectatorPresence(ctx, gameID, watcherID, true)
+	if err != nil {
+		logger.Warn("failed to register spectator", "game_id", gameID, "error", err)
+	}
+	defer func() {
+		// The request context is already done when the spectator leaves
+		if _, err := setSpectatorPresence(context.WithoutCancel(ctx), gameID, watcherID, false); err != nil {
+			logger.Warn("failed to unregister spectator", "game_id", gameID, "error", err)
+		}
+	}()
+
+	flusher, ok := startSSE(w)
+	if !ok {
+		return
+	}
+
+	poll := time.NewTicker(spectatorPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	expireForSpectator(game)
+	if err := writeSSE(w, flusher, "state", newSpectatorView(game, spectators)); err != nil {
+		return
+	}
+	sentMoves, sentOver := game.Moves, game.GameOver
+
+	for !sentOver {
+		select {
+		case <-ctx.Done():
+			return
+		case <-serverClosing:
+			return
+		case <-heartbeat.C:
+			if spectators, err = setSpectatorPresence(ctx, gameID, watcherID, true); err != nil {
+				logger.Warn("failed to refresh spectator", "game_id", gameID, "error", err)
+			}
+			if err := writeSSEHeartbeat(w, flusher); err != nil {
+				return
+			}
+			continue
+		case <-wake:
+			latestGames.Lock()
+			latest, ok := latestGames.games[gameID]
+			latestGames.Unlock()
+			if !ok {
+				continue
+			}
+			game = cloneGameState(latest)
+		case <-poll.C:
+			latest, err := loadGameSession(ctx, gameID)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("spectator stream failed to reload game", "game_id", gameID, "error", err)
+				}
+				return
+			}
+			game = latest
+		}
+		expireForSpectator(game)
+
+		// Storage may lag behind what was already sent from this pod
+		if game.Moves < sentMoves || (game.Moves == sentMoves && game.GameOver == sentOver) {
+			continue
+		}
+		if game.Moves == sentMoves+1 && game.LastMove != nil {
+			if err := writeSSE(w, flusher, "move", game.LastMove); err != nil {
+				return
+			}
+		}
+		if err := writeSSE(w, flusher, "state", newSpectatorView(game, spectators)); err != nil {
+			return
+		}
+		sentMoves, sentOver = game.Moves, game.GameOver
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sseHeartbeat keeps idle event streams open through proxies with short read
+// timeouts (the nginx ingress uses 30s)
+const sseHeartbeat = 15 * time.Second
+
+// serverClosing is closed when the server starts shutting down. Shutdown
+// doesn't cancel requests in flight, so event streams select on it as well as
+// the request context, or they would hold shutdown open until it times out.
+var serverClosing = make(chan struct{})
+
+// closeStreams ends every event stream; the server calls it on shutdown
+func closeStreams() {
+	close(serverClosing)
+}
+
+// eventBroker wakes local subscribers when something they watch changes. It
+// only reaches this pod, so streams also poll storage for changes made
+// elsewhere.
+type eventBroker struct {
+	mu   sync.Mutex
+	subs map[string]map[chan struct{}]bool
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subs: make(map[string]map[chan struct{}]bool)}
+}
+
+// Subscribe returns a channel that receives a value whenever key is
+// published, and a function to unsubscribe
+func (b *eventBroker) Subscribe(key string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	b.mu.Lock()
+	if b.subs[key] == nil {
+		b.subs[key] = make(map[chan struct{}]bool)
+	}
+	b.subs[key][ch] = true
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs[key], ch)
+		if len(b.subs[key]) == 0 {
+			delete(b.subs, key)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Publish wakes every subscriber of key. Slow subscribers get one pending
+// wake-up rather than a backlog.
+func (b *eventBroker) Publish(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Count returns the number of local subscribers of key
+func (b *eventBroker) Count(key string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs[key])
+}
+
+// startSSE prepares w for a server-sent event stream. It reports false (after
+// writing an error) if the connection can't stream.
+func startSSE(w http.ResponseWriter) (http.Flusher, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // don't let nginx buffer events
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return flusher, true
+}
+
+// writeSSE sends one event with data encoded as JSON
+func writeSSE(w http.ResponseWriter, flusher http.Flusher, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+// writeSSEHeartbeat sends a comment line that clients ignore
+func writeSSEHeartbeat(w http.ResponseWriter, flusher http.Flusher) error {
+	if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
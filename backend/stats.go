@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// scoreHistogramBuckets is roughly how many buckets the score histogram has
+	scoreHistogramBuckets = 20
+	// maxCachedStats caps how many filtered results are kept between refreshes
+	maxCachedStats = 100
+)
+
+// statsRefreshInterval is how often leaderboard statistics are recomputed
+// from the whole leaderboard (STATS_REFRESH_INTERVAL)
+var statsRefreshInterval = durationFromEnv("STATS_REFRESH_INTERVAL", 5*time.Minute)
+
+// LeaderboardStats describes the scores matching a leaderboard filter
+type LeaderboardStats struct {
+	GeneratedAt  time.Time    `json:"generatedAt"`
+	From         *time.Time   `json:"from,omitempty"`
+	To           *time.Time   `json:"to,omitempty"`
+	TotalPlayers int          `json:"totalPlayers"`
+	TotalGames   int          `json:"totalGames"`
+	HighestScore int          `json:"highestScore"`
+	AverageScore float64      `json:"averageScore"`
+	Score        Distribution `json:"score"`
+	ScoreBuckets []ScoreCount `json:"scoreHistogram"`
+	// MaxTiles and the win rate only count games submitted with their game
+	// ID, as the server doesn't know how other games ended
+	MaxTiles         []TileCount `json:"maxTiles"`
+	GamesWithOutcome int         `json:"gamesWithOutcome"`
+	Wins             int         `json:"wins"`
+	WinRate          float64     `json:"winRate"`
+	AverageDuration  float64     `json:"averageDuration"` // seconds
+	AverageMoves     float64     `json:"averageMoves"`
+	GamesPerDay      []DayCount  `json:"gamesPerDay"`
+
+	Partitions map[string]*LeaderboardStats `json:"partitions,omitempty"`
+}
+
+// Distribution describes a set of scores
+type Distribution struct {
+	Min    int     `json:"min"`
+	P10    int     `json:"p10"`
+	P25    int     `json:"p25"`
+	Median int     `json:"median"`
+	P75    int     `json:"p75"`
+	P90    int     `json:"p90"`
+	P99    int     `json:"p99"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+}
+
+// ScoreCount is how many games scored in [From, To)
+type ScoreCount struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Games int `json:"games"`
+}
+
+// TileCount is how many games ended with a given max tile
+type TileCount struct {
+	Tile     int     `json:"tile"`
+	Games    int     `json:"games"`
+	Fraction float64 `json:"fraction"`
+	// AtLeast is the fraction of games that reached this tile or higher
+	AtLeast float64 `json:"atLeast"`
+}
+
+// DayCount is how many games were played on a day (UTC)
+type DayCount struct {
+	Date  string `json:"date"`
+	Games int    `json:"games"`
+}
+
+// statsCache holds the leaderboard as of the last refresh and the statistics
+// computed from it so far, by filter
+var statsCache struct {
+	sync.Mutex
+	entries     []LeaderboardEntry
+	refreshedAt time.Time
+	results     map[string]*LeaderboardStats // by filter
+}
+
+// initStats computes the statistics once and keeps refreshing them
+func initStats() {
+	if err := refreshStats(context.Background()); err != nil {
+		slog.Error("failed to compute leaderboard statistics", "error", err)
+	}
+	go func() {
+		for range time.Tick(statsRefreshInterval) {
+			if err := refreshStats(context.Background()); err != nil {
+				slog.Error("failed to refresh leaderboard statistics", "error", err)
+			}
+		}
+	}()
+	slog.Info("leaderboard statistics enabled", "refresh_interval", statsRefreshInterval)
+}
+
+// refreshStats reloads the whole leaderboard and drops cached statistics. If
+// the leaderboard can't be loaded the cached statistics are kept.
+func refreshStats(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "leaderboard.refreshStats")
+	defer func() { endSpan(span, err) }()
+
+	if err := globalLeaderboard.loadFromPersistentStorage(ctx); err != nil {
+		return err
+	}
+
+	globalLeaderboard.mu.RLock()
+	entries := append([]LeaderboardEntry(nil), globalLeaderboard.entries...)
+	globalLeaderboard.mu.RUnlock()
+
+	statsCache.Lock()
+	statsCache.entries = entries
+	statsCache.refreshedAt = time.Now()
+	statsCache.results = make(map[string]*LeaderboardStats)
+	statsCache.Unlock()
+	slog.Debug("leaderboard statistics refreshed", "entries", len(entries))
+	return nil
+}
+
+// cachedStats returns the statistics for filter as of the last refresh
+func cachedStats(filter leaderboardFilter) *LeaderboardStats {
+	key := fmt.Sprintf("%+v", filter)
+
+	statsCache.Lock()
+	entries, refreshedAt := statsCache.entries, statsCache.refreshedAt
+	if stats, ok := statsCache.results[key]; ok {
+		statsCache.Unlock()
+		return stats
+	}
+	statsCache.Unlock()
+
+	// Computed outside the lock; the snapshot is never modified
+	stats := computeStats(entries, filter, refreshedAt)
+
+	statsCache.Lock()
+	defer statsCache.Unlock()
+	if statsCache.refreshedAt.Equal(refreshedAt) {
+		if len(statsCache.results) >= maxCachedStats {
+			statsCache.results = make(map[string]*LeaderboardStats)
+		}
+		statsCache.results[key] = stats
+	}
+	return stats
+}
+
+// computeStats describes the entries matching filter, overall and for each
+// partition
+func computeStats(entries []LeaderboardEntry, filter leaderboardFilter, generatedAt time.Time) *LeaderboardStats {
+	matching := make([]LeaderboardEntry, 0, len(entries))
+	byPartition := make(map[string][]LeaderboardEntry)
+	for _, entry := range entries {
+		if filter.matches(entry) {
+			matching = append(matching, entry)
+			byPartition[entry.Partition] = append(byPartition[entry.Partition], entry)
+		}
+	}
+
+	stats := summarizeEntries(matching, generatedAt)
+	stats.Partitions = make(map[string]*LeaderboardStats, len(byPartition))
+	for partition, entries := range byPartition {
+		stats.Partitions[partition] = summarizeEntries(entries, generatedAt)
+	}
+
+	if !filter.Period.From.IsZero() {
+		stats.From = &filter.Period.From
+	}
+	if !filter.Period.To.IsZero() {
+		stats.To = &filter.Period.To
+	}
+	return stats
+}
+
+// summarizeEntries computes the statistics of entries
+func summarizeEntries(entries []LeaderboardEntry, generatedAt time.Time) *LeaderboardStats {
+	s := &LeaderboardStats{
+		GeneratedAt:  generatedAt,
+		TotalGames:   len(entries),
+		ScoreBuckets: []ScoreCount{},
+		MaxTiles:     []TileCount{},
+		GamesPerDay:  []DayCount{},
+	}
+	if len(entries) == 0 {
+		return s
+	}
+
+	scores := make([]int, len(entries))
+	players := make(map[string]bool)
+	tiles := make(map[int]int)
+	days := make(map[string]int)
+	totalDuration, totalMoves := 0, 0
+	for i, entry := range entries {
+		scores[i] = entry.Score
+		players[entry.PlayerID] = true
+		totalDuration += entry.Duration
+		totalMoves += entry.Moves
+		days[entry.Timestamp.UTC().Format(time.DateOnly)]++
+		if entry.MaxTile > 0 {
+			tiles[entry.MaxTile]++
+			s.GamesWithOutcome++
+			if entry.Won {
+				s.Wins++
+			}
+		}
+	}
+
+	n := float64(len(entries))
+	s.TotalPlayers = len(players)
+	s.Score = distribution(scores)
+	s.HighestScore = s.Score.Max
+	s.AverageScore = s.Score.Mean
+	s.ScoreBuckets = scoreHistogram(scores, s.Score.Max)
+	s.AverageDuration = float64(totalDuration) / n
+	s.AverageMoves = float64(totalMoves) / n
+	if s.GamesWithOutcome > 0 {
+		s.WinRate = float64(s.Wins) / float64(s.GamesWithOutcome)
+	}
+
+	// Highest tile first, so AtLeast accumulates downwards
+	tileValues := make([]int, 0, len(tiles))
+	for t := range tiles {
+		tileValues = append(tileValues, t)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(tileValues)))
+	cumulative := 0
+	for _, t := range tileValues {
+		cumulative += tiles[t]
+		s.MaxTiles = append(s.MaxTiles, TileCount{
+			Tile:     t,
+			Games:    tiles[t],
+			Fraction: float64(tiles[t]) / float64(s.GamesWithOutcome),
+			AtLeast:  float64(cumulative) / float64(s.GamesWithOutcome),
+		})
+	}
+
+	for date, games := range days {
+		s.GamesPerDay = append(s.GamesPerDay, DayCount{Date: date, Games: games})
+	}
+	sort.Slice(s.GamesPerDay, func(i, j int) bool {
+		return s.GamesPerDay[i].Date < s.GamesPerDay[j].Date
+	})
+	return s
+}
+
+func distribution(samples []int) Distribution {
+	sorted := append([]int(nil), samples...)
+	sort.Ints(sorted)
+
+	total := 0
+	for _, v := range sorted {
+		total += v
+	}
+
+	pct := func(p float64) int {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	return Distribution{
+		Min:    sorted[0],
+		P10:    pct(0.10),
+		P25:    pct(0.25),
+		Median: pct(0.50),
+		P75:    pct(0.75),
+		P90:    pct(0.90),
+		P99:    pct(0.99),
+		Max:    sorted[len(sorted)-1],
+		Mean:   float64(total) / float64(len(sorted)),
+	}
+}
+
+// scoreHistogram counts scores in equal buckets from zero, a round width
+// (1, 2 or 5 times a power of ten) wide, so there are about
+// scoreHistogramBuckets of them
+func scoreHistogram(scores []int, highest int) []ScoreCount {
+	width := 1
+search:
+	for magnitude := 1; ; magnitude *= 10 {
+		for _, step := range []int{1, 2, 5} {
+			if width = step * magnitude; highest/width < scoreHistogramBuckets {
+				break search
+			}
+		}
+	}
+
+	buckets := make([]ScoreCount, highest/width+1)
+	for i := range buckets {
+		buckets[i] = ScoreCount{From: i * width, To: (i + 1) * width}
+	}
+	for _, score := range scores {
+		buckets[max(score, 0)/width].Games++
+	}
+	return buckets
+}
+
+// statsRangeParams narrows filter to the from and to query parameters, each
+// an RFC 3339 time or a date (to includes the whole day), writing an error
+// response if either is invalid
+func statsRangeParams(w http.ResponseWriter, r *http.Request, filter leaderboardFilter) (leaderboardFilter, bool) {
+	parse := func(name string, endOfDay bool) (time.Time, bool) {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			return time.Time{}, true
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, true
+		}
+		if t, err := time.Parse(time.DateOnly, v); err == nil {
+			if endOfDay {
+				t = t.AddDate(0, 0, 1)
+			}
+			return t, true
+		}
+		http.Error(w, fmt.Sprintf("Invalid %s: use an RFC 3339 time or YYYY-MM-DD", name), http.StatusBadRequest)
+		return time.Time{}, false
+	}
+
+	from, ok := parse("from", false)
+	if !ok {
+		return filter, false
+	}
+	to, ok := parse("to", true)
+	if !ok {
+		return filter, false
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return filter, false
+	}
+
+	// The range applies within the season asked for
+	if from.After(filter.Period.From) {
+		filter.Period.From = from
+	}
+	if !to.IsZero() && (filter.Period.To.IsZero() || to.Before(filter.Period.To)) {
+		filter.Period.To = to
+	}
+	return filter, true
+}
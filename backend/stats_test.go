@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDistribution(t *testing.T) {
+	samples := make([]int, 101)
+	for i := range samples {
+		samples[i] = (100 - i) * 10 // unsorted on purpose
+	}
+	d := distribution(samples)
+	want := Distribution{Min: 0, P10: 100, P25: 250, Median: 500, P75: 750, P90: 900, P99: 990, Max: 1000, Mean: 500}
+	if d != want {
+		t.Errorf("distribution = %+v, want %+v", d, want)
+	}
+	if d := distribution([]int{7}); d.Min != 7 || d.Median != 7 || d.P99 != 7 || d.Mean != 7 {
+		t.Errorf("distribution of one sample = %+v", d)
+	}
+}
+
+func TestScoreHistogram(t *testing.T) {
+	tests := []struct {
+		highest int
+		width   int
+	}{
+		{0, 1},
+		{15, 1},
+		{19, 1},
+		{20, 2},
+		{99, 5},
+		{1999, 100},
+		{2000, 200},
+		{123456, 10000},
+	}
+	for _, tt := range tests {
+		buckets := scoreHistogram([]int{0, tt.highest}, tt.highest)
+		if width := buckets[0].To - buckets[0].From; width != tt.width {
+			t.Errorf("highest %d: width %d, want %d", tt.highest, width, tt.width)
+		}
+		if len(buckets) > scoreHistogramBuckets {
+			t.Errorf("highest %d: %d buckets, want at most %d", tt.highest, len(buckets), scoreHistogramBuckets)
+		}
+		last := buckets[len(buckets)-1]
+		if tt.highest < last.From || tt.highest >= last.To || last.Games == 0 || buckets[0].Games == 0 {
+			t.Errorf("highest %d: buckets %+v", tt.highest, buckets)
+		}
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []LeaderboardEntry{
+		{PlayerID: "a", Score: 1000, Duration: 60, Moves: 100, Timestamp: day, MaxTile: 128},
+		{PlayerID: "a", Score: 3000, Duration: 120, Moves: 300, Timestamp: day, MaxTile: 256, Won: false},
+		{PlayerID: "b", Score: 20000, Duration: 600, Moves: 1000, Timestamp: day.AddDate(0, 0, 1), MaxTile: 2048, Won: true},
+		{PlayerID: "c", Score: 2000, Duration: 90, Moves: 200, Timestamp: day.AddDate(0, 0, 1)}, // submitted before game IDs
+		{PlayerID: "d", Score: 5000, Timestamp: day, Mode: "time-attack", MaxTile: 512},
+		{PlayerID: "e", Score: 9999, Timestamp: day, Moderation: moderationPending},
+	}
+	for i := range entries {
+		entries[i].fillPartition()
+	}
+
+	s := computeStats(entries, leaderboardFilter{BoardSize: 4}, day)
+	if s.TotalGames != 4 || s.TotalPlayers != 3 || s.HighestScore != 20000 {
+		t.Errorf("classic: %d games, %d players, highest %d; want 4, 3, 20000", s.TotalGames, s.TotalPlayers, s.HighestScore)
+	}
+	if s.GamesWithOutcome != 3 || s.Wins != 1 || s.WinRate != 1.0/3 {
+		t.Errorf("classic outcomes: %d games, %d wins, rate %v", s.GamesWithOutcome, s.Wins, s.WinRate)
+	}
+	if len(s.MaxTiles) != 3 || s.MaxTiles[0].Tile != 2048 || s.MaxTiles[2].AtLeast != 1 {
+		t.Errorf("classic max tiles = %+v", s.MaxTiles)
+	}
+	if len(s.GamesPerDay) != 2 || s.GamesPerDay[0] != (DayCount{Date: "2026-03-01", Games: 2}) {
+		t.Errorf("games per day = %+v", s.GamesPerDay)
+	}
+	if s.AverageMoves != 400 {
+		t.Errorf("average moves = %v, want 400", s.AverageMoves)
+	}
+	if len(s.Partitions) != 1 || s.Partitions["classic#4x4"].TotalGames != 4 {
+		t.Errorf("partitions = %v", s.Partitions)
+	}
+
+	// A ruleset spans its modes, each with its own partition
+	s = computeStats(entries, leaderboardFilter{Ruleset: "classic", BoardSize: 4}, day)
+	if s.TotalGames != 5 || len(s.Partitions) != 2 || s.Partitions["time-attack#4x4"].TotalGames != 1 {
+		t.Errorf("classic ruleset: %d games, partitions %v", s.TotalGames, s.Partitions)
+	}
+
+	period := seasonRange{From: day.AddDate(0, 0, 1)}
+	s = computeStats(entries, leaderboardFilter{BoardSize: 4, Period: period}, day)
+	if s.TotalGames != 2 || s.From == nil || !s.From.Equal(period.From) || s.To != nil {
+		t.Errorf("period: %d games, from %v, to %v", s.TotalGames, s.From, s.To)
+	}
+
+	if s := computeStats(nil, leaderboardFilter{BoardSize: 4}, day); s.TotalGames != 0 || s.MaxTiles == nil || s.ScoreBuckets == nil {
+		t.Errorf("no entries: %+v", s)
+	}
+}
+
+func TestStatsRangeParams(t *testing.T) {
+	season := seasonRange{
+		From: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		query    string
+		from, to time.Time
+		ok       bool
+	}{
+		{"", season.From, season.To, true},
+		{"from=2026-03-10", time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), season.To, true},
+		{"to=2026-03-10", season.From, time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC), true},
+		{"from=2026-03-10T06:00:00Z&to=2026-03-10T07:00:00Z", time.Date(2026, 3, 10, 6, 0, 0, 0, time.UTC), time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC), true},
+		{"from=2026-01-01&to=2026-12-31", season.From, season.To, true}, // within the season
+		{"from=2026-03-10&to=2026-03-09", time.Time{}, time.Time{}, false},
+		{"from=yesterday", time.Time{}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/leaderboard/stats?"+tt.query, nil)
+		f, ok := statsRangeParams(rec, r, leaderboardFilter{Period: season})
+		if ok != tt.ok {
+			t.Errorf("%q: ok = %v, want %v (%d)", tt.query, ok, tt.ok, rec.Code)
+			continue
+		}
+		if ok && (!f.Period.From.Equal(tt.from) || !f.Period.To.Equal(tt.to)) {
+			t.Errorf("%q: period %v to %v, want %v to %v", tt.query, f.Period.From, f.Period.To, tt.from, tt.to)
+		}
+	}
+}
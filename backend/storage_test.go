@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// useFakeDynamoDB points dynamodbClient at a fake that answers GetItem,
+// PutItem, DeleteItem and leaderboard index queries over items until the test
+// ends. Every table shares the items. Like DynamoDB, it orders items with the
+// same score in no particular order (here by a hash of their ID) and pages by
+// Limit. Puts only understand the conditions putRecord uses.
+func useFakeDynamoDB(t *testing.T, items []map[string]types.AttributeValue) {
+	t.Helper()
+	var mu sync.Mutex
+
+	score := func(item map[string]types.AttributeValue) int {
+		n, _ := strconv.Atoi(item["score"].(*types.AttributeValueMemberN).Value)
+		return n
+	}
+	tieKey := func(id string) string {
+		return fmt.Sprintf("%x", sha1.Sum([]byte(id)))
+	}
+	id := func(item map[string]types.AttributeValue) string {
+		return item["id"].(*types.AttributeValueMemberS).Value
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Key                       map[string]map[string]string
+			Item                      map[string]map[string]any
+			ConditionExpression       string
+			ExpressionAttributeNames  map[string]string
+			ExpressionAttributeValues map[string]map[string]string
+			Limit                     int
+			ExclusiveStartKey         map[string]map[string]string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		mu.Lock()
+		defer mu.Unlock()
+		find := func(key string) int {
+			for i, item := range items {
+				if id(item) == key {
+					return i
+				}
+			}
+			return -1
+		}
+
+		switch target := r.Header.Get("X-Amz-Target"); target {
+		case "DynamoDB_20120810.GetItem":
+			out := map[string]any{}
+			if i := find(in.Key["id"]["S"]); i >= 0 {
+				out["Item"] = attributeValuesJSON(items[i])
+			}
+			json.NewEncoder(w).Encode(out)
+			return
+		case "DynamoDB_20120810.PutItem":
+			item := attributeValuesFromJSON(in.Item)
+			i := find(id(item))
+			ok := true
+			switch in.ConditionExpression {
+			case "":
+			case "attribute_not_exists(id)":
+				ok = i < 0
+			case "version = :version":
+				ok = false
+				if i >= 0 {
+					version, _ := items[i]["version"].(*types.AttributeValueMemberN)
+					ok = version != nil && version.Value == in.ExpressionAttributeValues[":version"]["N"]
+				}
+			default:
+				http.Error(w, "unsupported condition "+in.ConditionExpression, http.StatusBadRequest)
+				return
+			}
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{
+					"__type":  "com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException",
+					"message": "The conditional request failed",
+				})
+				return
+			}
+			if i >= 0 {
+				items[i] = item
+			} else {
+				items = append(items, item)
+			}
+			json.NewEncoder(w).Encode(map[string]any{})
+			return
+		case "DynamoDB_20120810.DeleteItem":
+			if i := find(in.Key["id"]["S"]); i >= 0 {
+				items = append(items[:i], items[i+1:]...)
+			}
+			json.NewEncoder(w).Encode(map[string]any{})
+			return
+		case "DynamoDB_20120810.Query":
+		default:
+			http.Error(w, "unsupported operation "+target, http.StatusBadRequest)
+			return
+		}
+
+		attr := in.ExpressionAttributeNames["#partition"]
+		value := in.ExpressionAttributeValues[":partition"]["S"]
+		var matching []map[string]types.AttributeValue
+		for _, item := range items {
+			if s, ok := item[attr].(*types.AttributeValueMemberS); ok && s.Value == value {
+				matching = append(matching, item)
+			}
+		}
+		before := func(a, b map[string]types.AttributeValue) bool {
+			if score(a) != score(b) {
+				return score(a) > score(b)
+			}
+			return tieKey(id(a)) > tieKey(id(b))
+		}
+		sort.Slice(matching, func(i, j int) bool { return before(matching[i], matching[j]) })
+
+		start := 0
+		if k := in.ExclusiveStartKey; k != nil {
+			key := map[string]types.AttributeValue{
+				"score": &types.AttributeValueMemberN{Value: k["score"]["N"]},
+				"id":    &types.AttributeValueMemberS{Value: k["id"]["S"]},
+			}
+			start = sort.Search(len(matching), func(i int) bool { return before(key, matching[i]) })
+		}
+		end := min(len(matching), start+in.Limit)
+
+		out := map[string]any{"Count": end - start}
+		page := make([]map[string]any, 0, end-start)
+		for _, item := range matching[start:end] {
+			page = append(page, attributeValuesJSON(item))
+		}
+		out["Items"] = page
+		if end < len(matching) {
+			last := matching[end-1]
+			out["LastEvaluatedKey"] = attributeValuesJSON(map[string]types.AttributeValue{
+				attr: last[attr], "score": last["score"], "id": last["id"],
+			})
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	t.Cleanup(srv.Close)
+
+	saved := dynamodbClient
+	dynamodbClient = dynamodb.New(dynamodb.Options{
+		BaseEndpoint: aws.String(srv.URL),
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	t.Cleanup(func() { dynamodbClient = saved })
+}
+
+// attributeValuesJSON encodes an item the way the DynamoDB JSON protocol does
+func attributeValuesJSON(item map[string]types.AttributeValue) map[string]any {
+	out := make(map[string]any, len(item))
+	for name, value := range item {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			out[name] = map[string]string{"S": v.Value}
+		case *types.AttributeValueMemberN:
+			out[name] = map[string]string{"N": v.Value}
+		case *types.AttributeValueMemberBOOL:
+			out[name] = map[string]bool{"BOOL": v.Value}
+		default:
+			panic(fmt.Sprintf("unsupported attribute type %T", value))
+		}
+	}
+	return out
+}
+
+// attributeValuesFromJSON decodes an item sent in the DynamoDB JSON protocol
+func attributeValuesFromJSON(item map[string]map[string]any) map[string]types.AttributeValue {
+	out := make(map[string]types.AttributeValue, len(item))
+	for name, value := range item {
+		switch {
+		case value["S"] != nil:
+			out[name] = &types.AttributeValueMemberS{Value: value["S"].(string)}
+		case value["N"] != nil:
+			out[name] = &types.AttributeValueMemberN{Value: value["N"].(string)}
+		case value["BOOL"] != nil:
+			out[name] = &types.AttributeValueMemberBOOL{Value: value["BOOL"].(bool)}
+		default:
+			panic(fmt.Sprintf("unsupported attribute %v", value))
+		}
+	}
+	return out
+}
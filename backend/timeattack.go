@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// timeAttackMode is appended to the leaderboard mode of time-attack games
+const timeAttackMode = "time-attack"
+
+// timeAttackLimit is how long every time-attack game lasts. It is the same for
+// all players so their scores can be ranked together.
+var timeAttackLimit = durationFromEnv("TIME_ATTACK_LIMIT", 3*time.Minute)
+
+// TimeAttackState is the server's clock for a time-attack game. The client's
+// own timer is only for display; moves after Deadline are refused.
+type TimeAttackState struct {
+	StartedAt   time.Time  `json:"startedAt"`
+	Deadline    time.Time  `json:"deadline"`
+	EndedAt     *time.Time `json:"endedAt,omitempty"`
+	Expired     bool       `json:"expired"`
+	Moves       int        `json:"moves"`
+	RemainingMs int64      `json:"remainingMs"` // as of the response it was sent in
+}
+
+func newTimeAttackState(start time.Time) *TimeAttackState {
+	return &TimeAttackState{
+		StartedAt:   start,
+		Deadline:    start.Add(timeAttackLimit),
+		RemainingMs: timeAttackLimit.Milliseconds(),
+	}
+}
+
+// expire ends game if its deadline has passed. It reports whether it did.
+func (t *TimeAttackState) expire(game *GameState, now time.Time) bool {
+	if game.GameOver || now.Before(t.Deadline) {
+		return false
+	}
+	end := t.Deadline
+	t.EndedAt = &end
+	t.Expired = true
+	t.RemainingMs = 0
+	game.GameOver = true
+	return true
+}
+
+// afterMove counts a move and stops the clock if it ended the game
+func (t *TimeAttackState) afterMove(game *GameState, now time.Time) {
+	t.Moves++
+	if game.GameOver && t.EndedAt == nil {
+		t.EndedAt = &now
+	}
+}
+
+// tick refreshes RemainingMs before the state is sent to the client
+func (t *TimeAttackState) tick(now time.Time) {
+	if t.EndedAt != nil {
+		t.RemainingMs = 0
+		return
+	}
+	t.RemainingMs = t.Deadline.Sub(now).Milliseconds()
+	if t.RemainingMs < 0 {
+		t.RemainingMs = 0
+	}
+}
+
+// Duration is how long the game was played, as measured by the server
+func (t *TimeAttackState) Duration() time.Duration {
+	if t.EndedAt == nil {
+		return 0
+	}
+	return t.EndedAt.Sub(t.StartedAt)
+}
+
+// isTimeAttackMode reports whether mode names a time-attack leaderboard
+func isTimeAttackMode(mode string) bool {
+	return mode == timeAttackMode || strings.HasSuffix(mode, "+"+timeAttackMode)
+}
@@ -0,0 +1,929 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"2048game/engine"
+)
+
+// Tournament formats
+const (
+	formatPoints      = "points"      // every round awards points by rank
+	formatElimination = "elimination" // the bottom half drops out after each round
+)
+
+// Tournament statuses, derived from the schedule
+const (
+	tournamentUpcoming     = "upcoming"
+	tournamentRegistration = "registration"
+	tournamentRunning      = "running"
+	tournamentFinished     = "finished"
+	tournamentClosed       = "closed"
+)
+
+const (
+	// Tournaments are single records, which caps how much they can hold
+	maxTournamentPlayers = 256
+	maxTournamentRounds  = 8
+	maxTournamentName    = 64
+)
+
+var (
+	errTournamentNotFound = errors.New("tournament not found")
+	errTournamentState    = errors.New("tournament is not in the right state")
+	errTournamentFull     = errors.New("tournament is full")
+	errNotRegistered      = errors.New("not registered for this tournament")
+	errEliminated         = errors.New("eliminated from this tournament")
+	errAlreadyRegistered  = errors.New("player is already registered")
+)
+
+// Tournament is a scheduled competition. Players register while registration
+// is open, then play one game per round; everyone in a round gets the same
+// seed. It is stored as a single record and changed through updateTournament.
+type Tournament struct {
+	ID                 string                  `json:"id"`
+	Name               string                  `json:"name"`
+	Format             string                  `json:"format"`
+	Rules              engine.Rules            `json:"rules"`
+	RegistrationOpens  time.Time               `json:"registrationOpens"`
+	RegistrationCloses time.Time               `json:"registrationCloses"`
+	Rounds             []TournamentRound       `json:"rounds"`
+	Participants       []TournamentParticipant `json:"participants"`
+	Status             string                  `json:"status"` // as of the response it was sent in
+	CreatedAt          time.Time               `json:"createdAt"`
+	ClosedAt           *time.Time              `json:"closedAt,omitempty"`
+}
+
+// TournamentRound is one scheduled round and, once settled, its results
+type TournamentRound struct {
+	Number    int                     `json:"number"`
+	StartsAt  time.Time               `json:"startsAt"`
+	EndsAt    time.Time               `json:"endsAt"`
+	Seed      int64                   `json:"seed,omitempty"` // kept secret until the round is over
+	Cancelled bool                    `json:"cancelled,omitempty"`
+	Settled   bool                    `json:"settled"`
+	Results   []TournamentRoundResult `json:"results,omitempty"`
+}
+
+// TournamentRoundResult is a player's standing in one round
+type TournamentRoundResult struct {
+	Rank     int    `json:"rank"`
+	PlayerID string `json:"playerId"`
+	Score    int    `json:"score"`
+	MaxTile  int    `json:"maxTile"`
+	Moves    int    `json:"moves"`
+	Played   bool   `json:"played"`
+	Points   int    `json:"points,omitempty"`
+	Advanced bool   `json:"advanced,omitempty"`
+}
+
+// TournamentParticipant is a registered player and their running totals
+type TournamentParticipant struct {
+	PlayerID     string    `json:"playerId"`
+	Name         string    `json:"name"`
+	Moderation   string    `json:"moderation,omitempty"` // "pending" or "hidden" shows heldName instead of Name
+	Token        string    `json:"token,omitempty"`      // proves registration; only shown to its owner
+	RegisteredAt time.Time `json:"registeredAt"`
+	Points       int       `json:"points"`
+	TotalScore   int       `json:"totalScore"`
+	EliminatedIn int       `json:"eliminatedIn,omitempty"` // round number
+}
+
+// TournamentStanding is a player's overall place
+type TournamentStanding struct {
+	Rank         int    `json:"rank"`
+	PlayerID     string `json:"playerId"`
+	Name         string `json:"name"`
+	Points       int    `json:"points"`
+	TotalScore   int    `json:"totalScore"`
+	EliminatedIn int    `json:"eliminatedIn,omitempty"`
+}
+
+// TournamentRun is a player's game in one round. Runs are separate records so
+// players don't contend for the tournament record on every move.
+type TournamentRun struct {
+	GameID    string    `json:"gameId"`
+	PlayerID  string    `json:"playerId"`
+	Score     int       `json:"score"`
+	MaxTile   int       `json:"maxTile"`
+	Moves     int       `json:"moves"`
+	Finished  bool      `json:"finished"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TournamentInfo links a game session to its tournament round
+type TournamentInfo struct {
+	TournamentID string `json:"tournamentId"`
+	Round        int    `json:"round"`
+}
+
+func tournamentKey(id string) string {
+	return "tournament#" + id
+}
+
+func tournamentRunKey(id string, round int, playerID string) string {
+	return "tournament#" + id + "#" + strconv.Itoa(round) + "#" + playerID
+}
+
+// tournamentIndexKey holds the IDs of every tournament, newest last
+const tournamentIndexKey = "tournaments"
+
+// status works out where t is in its schedule
+func (t *Tournament) status(now time.Time) string {
+	switch {
+	case t.ClosedAt != nil:
+		return tournamentClosed
+	case now.Before(t.RegistrationOpens):
+		return tournamentUpcoming
+	case now.Before(t.RegistrationCloses):
+		return tournamentRegistration
+	}
+	for _, round := range t.Rounds {
+		if !round.Settled {
+			return tournamentRunning
+		}
+	}
+	return tournamentFinished
+}
+
+// currentRound returns the round being played at now, if any
+func (t *Tournament) currentRound(now time.Time) *TournamentRound {
+	if t.ClosedAt != nil {
+		return nil
+	}
+	for i := range t.Rounds {
+		r := &t.Rounds[i]
+		if !r.Cancelled && !now.Before(r.StartsAt) && now.Before(r.EndsAt) {
+			return r
+		}
+	}
+	return nil
+}
+
+// round returns round number n
+func (t *Tournament) round(n int) *TournamentRound {
+	if n < 1 || n > len(t.Rounds) {
+		return nil
+	}
+	return &t.Rounds[n-1]
+}
+
+// unsettledRound returns the first round that is over but hasn't been scored
+func (t *Tournament) unsettledRound(now time.Time) *TournamentRound {
+	for i := range t.Rounds {
+		r := &t.Rounds[i]
+		if r.Settled {
+			continue
+		}
+		if r.Cancelled || !now.Before(r.EndsAt) {
+			return r
+		}
+		return nil
+	}
+	return nil
+}
+
+// participant returns the player holding token
+func (t *Tournament) participant(token string) *TournamentParticipant {
+	if token == "" {
+		return nil
+	}
+	for i := range t.Participants {
+		if t.Participants[i].Token == token {
+			return &t.Participants[i]
+		}
+	}
+	return nil
+}
+
+// eligible returns the players still in the tournament when round starts
+func (t *Tournament) eligible(round int) []*TournamentParticipant {
+	var players []*TournamentParticipant
+	for i := range t.Participants {
+		p := &t.Participants[i]
+		if p.EliminatedIn == 0 || p.EliminatedIn >= round {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+// settleRound scores round from the players' runs. Players without a run get
+// nothing; in an elimination they drop out.
+func (t *Tournament) settleRound(round *TournamentRound, runs map[string]TournamentRun) {
+	round.Settled = true
+	if round.Cancelled {
+		return
+	}
+
+	players := t.eligible(round.Number)
+	results := make([]TournamentRoundResult, 0, len(players))
+	for _, p := range players {
+		run, played := runs[p.PlayerID]
+		results = append(results, TournamentRoundResult{
+			PlayerID: p.PlayerID,
+			Score:    run.Score,
+			MaxTile:  run.MaxTile,
+			Moves:    run.Moves,
+			Played:   played,
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Played != b.Played {
+			return a.Played
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.MaxTile != b.MaxTile {
+			return a.MaxTile > b.MaxTile
+		}
+		return a.Moves < b.Moves
+	})
+
+	// Elimination keeps the top half, rounded up
+	advancing := (len(results) + 1) / 2
+	for i := range results {
+		res := &results[i]
+		res.Rank = i + 1
+		p := t.participantByID(res.PlayerID)
+		p.TotalScore += res.Score
+		switch t.Format {
+		case formatPoints:
+			if res.Played {
+				res.Points = len(results) - i
+				p.Points += res.Points
+			}
+		case formatElimination:
+			res.Advanced = res.Played && i < advancing
+			if !res.Advanced {
+				p.EliminatedIn = round.Number
+			}
+		}
+	}
+	round.Results = results
+}
+
+func (t *Tournament) participantByID(playerID string) *TournamentParticipant {
+	for i := range t.Participants {
+		if t.Participants[i].PlayerID == playerID {
+			return &t.Participants[i]
+		}
+	}
+	return nil
+}
+
+// standings ranks the participants overall: by points, or by how long they
+// lasted in an elimination, with total score breaking ties
+func (t *Tournament) standings() []TournamentStanding {
+	lasted := func(p TournamentParticipant) int {
+		if p.EliminatedIn == 0 {
+			return len(t.Rounds) + 1
+		}
+		return p.EliminatedIn
+	}
+	players := append([]TournamentParticipant(nil), t.Participants...)
+	sort.SliceStable(players, func(i, j int) bool {
+		a, b := players[i], players[j]
+		if t.Format == formatElimination && lasted(a) != lasted(b) {
+			return lasted(a) > lasted(b)
+		}
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		return a.TotalScore > b.TotalScore
+	})
+
+	standings := make([]TournamentStanding, len(players))
+	for i, p := range players {
+		standings[i] = TournamentStanding{
+			Rank:         i + 1,
+			PlayerID:     p.PlayerID,
+			Name:         shownName(p.Name, p.Moderation),
+			Points:       p.Points,
+			TotalScore:   p.TotalScore,
+			EliminatedIn: p.EliminatedIn,
+		}
+	}
+	return standings
+}
+
+// public returns a copy of t that is safe to show to anyone: no tokens, no
+// held names, and no seeds for rounds that could still be played
+func (t Tournament) public(now time.Time) Tournament {
+	t.Status = t.status(now)
+	participants := make([]TournamentParticipant, len(t.Participants))
+	for i, p := range t.Participants {
+		p.Token = ""
+		p.Name = shownName(p.Name, p.Moderation)
+		participants[i] = p
+	}
+	t.Participants = participants
+	rounds := make([]TournamentRound, len(t.Rounds))
+	for i, r := range t.Rounds {
+		if !r.Settled {
+			r.Seed = 0
+		}
+		rounds[i] = r
+	}
+	t.Rounds = rounds
+	return t
+}
+
+// validate checks a tournament definition from the admin API
+func (t *Tournament) validate() error {
+	if t.Name == "" || len(t.Name) > maxTournamentName {
+		return fmt.Errorf("name must be 1 to %d characters", maxTournamentName)
+	}
+	if t.Format != formatPoints && t.Format != formatElimination {
+		return fmt.Errorf("format must be %q or %q", formatPoints, formatElimination)
+	}
+	if err := t.Rules.Validate(); err != nil {
+		return fmt.Errorf("invalid rules: %w", err)
+	}
+	if !t.RegistrationOpens.Before(t.RegistrationCloses) {
+		return errors.New("registration must close after it opens")
+	}
+	if len(t.Rounds) == 0 || len(t.Rounds) > maxTournamentRounds {
+		return fmt.Errorf("a tournament needs 1 to %d rounds", maxTournamentRounds)
+	}
+	prevEnd := t.RegistrationCloses
+	for i, r := range t.Rounds {
+		if r.StartsAt.Before(prevEnd) {
+			return fmt.Errorf("round %d starts before registration or the previous round ends", i+1)
+		}
+		if !r.StartsAt.Before(r.EndsAt) {
+			return fmt.Errorf("round %d must end after it starts", i+1)
+		}
+		prevEnd = r.EndsAt
+	}
+	return nil
+}
+
+// updateTournament applies change to tournament id
+func updateTournament(ctx context.Context, id string, change func(t *Tournament) error) (Tournament, error) {
+	return updateRecord(ctx, tournamentKey(id), 0, func(t *Tournament, exists bool) error {
+		if !exists {
+			return errTournamentNotFound
+		}
+		return change(t)
+	})
+}
+
+// loadTournament reads tournament id, first scoring any round that has ended.
+// Rounds are settled lazily by whoever looks next, one at a time since each
+// one decides who plays the next.
+func loadTournament(ctx context.Context, id string) (Tournament, error) {
+	var t Tournament
+	found, err := loadRecord(ctx, tournamentKey(id), &t)
+	if err != nil {
+		return t, err
+	}
+	if !found {
+		return t, errTournamentNotFound
+	}
+
+	for {
+		round := t.unsettledRound(time.Now())
+		if round == nil {
+			return t, nil
+		}
+		runs, err := loadTournamentRuns(ctx, &t, round)
+		if err != nil {
+			return t, err
+		}
+		number := round.Number
+		t, err = updateTournament(ctx, id, func(t *Tournament) error {
+			if round := t.round(number); !round.Settled {
+				t.settleRound(round, runs)
+			}
+			return nil
+		})
+		if err != nil {
+			return t, err
+		}
+		loggerFromContext(ctx).Info("tournament round settled", "tournament_id", id, "round", number, "players", len(runs))
+	}
+}
+
+// loadTournamentRuns reads the runs of every player eligible for round
+func loadTournamentRuns(ctx context.Context, t *Tournament, round *TournamentRound) (map[string]TournamentRun, error) {
+	runs := make(map[string]TournamentRun)
+	if round.Cancelled {
+		return runs, nil
+	}
+	for _, p := range t.eligible(round.Number) {
+		var run TournamentRun
+		found, err := loadRecord(ctx, tournamentRunKey(t.ID, round.Number, p.PlayerID), &run)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			runs[p.PlayerID] = run
+		}
+	}
+	return runs, nil
+}
+
+// newTournamentGameState starts a player's game for round. Everyone in the
+// round gets the same tiles in the same positions because they come from the
+// round's seed.
+func newTournamentGameState(t *Tournament, round *TournamentRound, player *TournamentParticipant) *GameState {
+	var empty engine.Board
+	return &GameState{
+		ID:         generateID(),
+		Game:       *engine.NewWithRules(engine.BoardSource(round.Seed, empty), t.Rules),
+		PlayerID:   player.PlayerID,
+		Tournament: &TournamentInfo{TournamentID: t.ID, Round: round.Number},
+		CreatedAt:  time.Now(),
+	}
+}
+
+// prepareTournamentMove seeds game's spawns from its round, or ends the game
+// if the round is over. It reports whether the move can go ahead.
+func prepareTournamentMove(ctx context.Context, game *GameState) (bool, error) {
+	var t Tournament
+	found, err := loadRecord(ctx, tournamentKey(game.Tournament.TournamentID), &t)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return false, errTournamentNotFound
+	}
+	round := t.round(game.Tournament.Round)
+	if round == nil || t.currentRound(time.Now()) != round {
+		game.GameOver = true
+		return false, nil
+	}
+	game.SetSource(engine.BoardSource(round.Seed, game.Board))
+	return true, nil
+}
+
+// reportTournamentRun records game's progress as the player's run. A failure
+// is only logged: the move itself has been saved.
+func reportTournamentRun(ctx context.Context, game *GameState) {
+	info := game.Tournament
+	run := TournamentRun{
+		GameID:    game.ID,
+		PlayerID:  game.PlayerID,
+		Score:     game.Score,
+		MaxTile:   game.Board.MaxTile(),
+		Moves:     game.Moves,
+		Finished:  game.GameOver,
+		UpdatedAt: time.Now(),
+	}
+	if err := saveRecord(ctx, tournamentRunKey(info.TournamentID, info.Round, game.PlayerID), run, 0); err != nil {
+		loggerFromContext(ctx).Error("failed to report tournament run", "game_id", game.ID, "tournament_id", info.TournamentID, "error", err)
+	}
+}
+
+// Tournament handlers
+
+// tournamentRequest is the body of every player action
+type tournamentRequest struct {
+	TournamentID string `json:"tournamentId"`
+	Token        string `json:"token"`
+	PlayerID     string `json:"playerId"`
+	Name         string `json:"name"`
+}
+
+// decodeTournamentRequest reads a POST body, writing an error response if it
+// can't
+func decodeTournamentRequest(w http.ResponseWriter, r *http.Request) (*tournamentRequest, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	var req tournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	return &req, true
+}
+
+// writeTournamentError maps tournament errors to HTTP responses
+func writeTournamentError(w http.ResponseWriter, r *http.Request, id string, err error) {
+	switch {
+	case errors.Is(err, errTournamentNotFound):
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+	case errors.Is(err, errNotRegistered):
+		http.Error(w, "Not registered for this tournament", http.StatusForbidden)
+	case errors.Is(err, errTournamentState), errors.Is(err, errTournamentFull),
+		errors.Is(err, errEliminated), errors.Is(err, errAlreadyRegistered):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, context.DeadlineExceeded):
+		loggerFromContext(r.Context()).Error("tournament storage timed out", "tournament_id", id, "error", err)
+		http.Error(w, "Storage timeout", http.StatusGatewayTimeout)
+	default:
+		loggerFromContext(r.Context()).Error("tournament update failed", "tournament_id", id, "error", err)
+		http.Error(w, "Failed to update tournament", http.StatusInternalServerError)
+	}
+}
+
+// writeTournament responds with the public tournament, plus the caller's own
+// registration (including its token) when they are in it
+func writeTournament(w http.ResponseWriter, t Tournament, token string) {
+	resp := map[string]interface{}{"tournament": t.public(time.Now())}
+	if me := t.participant(token); me != nil {
+		resp["you"] = me
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// tournamentsHandler lists every tournament without its participants
+func tournamentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var ids []string
+	if _, err := loadRecord(r.Context(), tournamentIndexKey, &ids); err != nil {
+		writeTournamentError(w, r, "", err)
+		return
+	}
+
+	type summary struct {
+		Tournament
+		PlayerCount int `json:"playerCount"`
+	}
+	now := time.Now()
+	list := make([]summary, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		t, err := loadTournament(r.Context(), ids[i])
+		if errors.Is(err, errTournamentNotFound) {
+			continue
+		}
+		if err != nil {
+			writeTournamentError(w, r, ids[i], err)
+			return
+		}
+		s := summary{Tournament: t.public(now), PlayerCount: len(t.Participants)}
+		s.Participants = nil
+		for j := range s.Rounds {
+			s.Rounds[j].Results = nil
+		}
+		list = append(list, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tournaments": list})
+}
+
+// registerTournamentHandler signs the caller up while registration is open
+func registerTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTournamentRequest(w, r)
+	if !ok {
+		return
+	}
+	name, err := normalizeName(req.Name)
+	if err != nil || req.PlayerID == "" {
+		http.Error(w, "playerId and a name of up to 32 characters are required", http.StatusBadRequest)
+		return
+	}
+	req.Name = name
+
+	me := TournamentParticipant{
+		PlayerID:     req.PlayerID,
+		Name:         req.Name,
+		Token:        generateToken(),
+		RegisteredAt: time.Now(),
+	}
+	// Standings are public, so a name on the deny list is held for review
+	if nameNeedsReview(name) {
+		me.Moderation = moderationPending
+	}
+	t, err := updateTournament(r.Context(), req.TournamentID, func(t *Tournament) error {
+		if t.status(time.Now()) != tournamentRegistration {
+			return errTournamentState
+		}
+		if t.participantByID(req.PlayerID) != nil {
+			return errAlreadyRegistered
+		}
+		if len(t.Participants) >= maxTournamentPlayers {
+			return errTournamentFull
+		}
+		t.Participants = append(t.Participants, me)
+		return nil
+	})
+	if err != nil {
+		writeTournamentError(w, r, req.TournamentID, err)
+		return
+	}
+
+	loggerFromContext(r.Context()).Info("player registered for tournament", "tournament_id", t.ID, "player_id", req.PlayerID)
+	writeTournament(w, t, me.Token)
+}
+
+// playTournamentHandler returns the caller's game for the current round,
+// starting it on first use. Each player gets one game per round.
+func playTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTournamentRequest(w, r)
+	if !ok {
+		return
+	}
+
+	t, err := loadTournament(r.Context(), req.TournamentID)
+	if err != nil {
+		writeTournamentError(w, r, req.TournamentID, err)
+		return
+	}
+	me := t.participant(req.Token)
+	if me == nil {
+		writeTournamentError(w, r, t.ID, errNotRegistered)
+		return
+	}
+	round := t.currentRound(time.Now())
+	if round == nil {
+		writeTournamentError(w, r, t.ID, errTournamentState)
+		return
+	}
+	if me.EliminatedIn != 0 && me.EliminatedIn < round.Number {
+		writeTournamentError(w, r, t.ID, errEliminated)
+		return
+	}
+
+	// Claim the round before creating its game, so retries get the same game
+	game := newTournamentGameState(&t, round, me)
+	run, err := updateRecord(r.Context(), tournamentRunKey(t.ID, round.Number, me.PlayerID), 0, func(run *TournamentRun, exists bool) error {
+		if !exists {
+			*run = TournamentRun{GameID: game.ID, PlayerID: me.PlayerID, UpdatedAt: time.Now()}
+		}
+		return nil
+	})
+	if err != nil {
+		writeTournamentError(w, r, t.ID, err)
+		return
+	}
+
+	if run.GameID == game.ID {
+		if err := saveGameSession(r.Context(), game); err != nil {
+			loggerFromContext(r.Context()).Error("failed to save tournament game", "game_id", game.ID, "error", err)
+			http.Error(w, "Failed to create game", http.StatusInternalServerError)
+			return
+		}
+		loggerFromContext(r.Context()).Info("tournament game started", "tournament_id", t.ID, "round", round.Number, "game_id", game.ID)
+	} else if game, err = loadGameSession(r.Context(), run.GameID); err != nil {
+		writeSessionLoadError(w, r, run.GameID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(game)
+}
+
+// tournamentStateHandler returns a tournament, and the caller's registration
+// when they pass their token
+func tournamentStateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	t, err := loadTournament(r.Context(), id)
+	if err != nil {
+		writeTournamentError(w, r, id, err)
+		return
+	}
+	writeTournament(w, t, r.URL.Query().Get("token"))
+}
+
+// tournamentResultsHandler returns the overall standings and each settled
+// round's results
+func tournamentResultsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	t, err := loadTournament(r.Context(), id)
+	if err != nil {
+		writeTournamentError(w, r, id, err)
+		return
+	}
+
+	pub := t.public(time.Now())
+	var rounds []TournamentRound
+	for _, round := range pub.Rounds {
+		if round.Settled {
+			rounds = append(rounds, round)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":        t.ID,
+		"name":      t.Name,
+		"format":    t.Format,
+		"status":    pub.Status,
+		"standings": t.standings(),
+		"rounds":    rounds,
+	})
+}
+
+// Tournament admin handlers
+
+// createTournamentHandler defines a new tournament. The body is a Tournament
+// with name, format, rules, the registration window and each round's
+// startsAt and endsAt; registration opens immediately if no time is given.
+func createTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var def Tournament
+	if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	t := Tournament{
+		ID:                 generateID(),
+		Name:               strings.TrimSpace(def.Name),
+		Format:             def.Format,
+		Rules:              def.Rules,
+		RegistrationOpens:  def.RegistrationOpens,
+		RegistrationCloses: def.RegistrationCloses,
+		Participants:       []TournamentParticipant{},
+		CreatedAt:          now,
+	}
+	if t.RegistrationOpens.IsZero() {
+		t.RegistrationOpens = now
+	}
+	for i, round := range def.Rounds {
+		t.Rounds = append(t.Rounds, TournamentRound{
+			Number:   i + 1,
+			StartsAt: round.StartsAt,
+			EndsAt:   round.EndsAt,
+			Seed:     generateSeed(),
+		})
+	}
+	if err := t.validate(); err != nil {
+		http.Error(w, "Invalid tournament: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := updateRecord(r.Context(), tournamentKey(t.ID), 0, func(rec *Tournament, exists bool) error {
+		if exists {
+			return errTournamentState
+		}
+		*rec = t
+		return nil
+	}); err != nil {
+		writeTournamentError(w, r, t.ID, err)
+		return
+	}
+	if _, err := updateRecord(r.Context(), tournamentIndexKey, 0, func(ids *[]string, _ bool) error {
+		*ids = append(*ids, t.ID)
+		return nil
+	}); err != nil {
+		writeTournamentError(w, r, t.ID, err)
+		return
+	}
+
+	loggerFromContext(r.Context()).Info("tournament created", "tournament_id", t.ID, "name", t.Name, "format", t.Format, "rounds", len(t.Rounds))
+	audit(r, "tournament.create", t.ID, map[string]any{"name": t.Name})
+	writeTournament(w, t, "")
+}
+
+// closeTournamentHandler ends a tournament early. A round in progress stops
+// now and is scored; rounds that haven't started are cancelled.
+func closeTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	_, err := updateTournament(r.Context(), req.ID, func(t *Tournament) error {
+		if t.ClosedAt != nil {
+			return errTournamentState
+		}
+		now := time.Now()
+		t.ClosedAt = &now
+		for i := range t.Rounds {
+			round := &t.Rounds[i]
+			switch {
+			case round.Settled:
+			case now.Before(round.StartsAt):
+				round.Cancelled = true
+			case now.Before(round.EndsAt):
+				round.EndsAt = now
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeTournamentError(w, r, req.ID, err)
+		return
+	}
+
+	// Loading settles whatever the close just ended
+	t, err := loadTournament(r.Context(), req.ID)
+	if err != nil {
+		writeTournamentError(w, r, req.ID, err)
+		return
+	}
+	loggerFromContext(r.Context()).Info("tournament closed", "tournament_id", t.ID)
+	audit(r, "tournament.close", t.ID, nil)
+	writeTournament(w, t, "")
+}
+
+// heldParticipant is a tournament registration whose name waits for review
+type heldParticipant struct {
+	TournamentID string `json:"tournamentId"`
+	TournamentParticipant
+}
+
+// heldParticipants returns the registrations waiting for review in every
+// tournament, oldest first
+func heldParticipants(ctx context.Context) ([]heldParticipant, error) {
+	var ids []string
+	if _, err := loadRecord(ctx, tournamentIndexKey, &ids); err != nil {
+		return nil, err
+	}
+
+	held := make([]heldParticipant, 0)
+	for _, id := range ids {
+		var t Tournament
+		if found, err := loadRecord(ctx, tournamentKey(id), &t); err != nil {
+			return nil, err
+		} else if !found {
+			continue
+		}
+		for _, p := range t.Participants {
+			if p.Moderation == moderationPending {
+				p.Token = ""
+				held = append(held, heldParticipant{TournamentID: id, TournamentParticipant: p})
+			}
+		}
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i].RegisteredAt.Before(held[j].RegisteredAt) })
+	return held, nil
+}
+
+// moderateParticipantHandler approves, renames or hides the name of a
+// tournament participant (see moderateName)
+func moderateParticipantHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TournamentID string `json:"tournamentId"`
+		PlayerID     string `json:"playerId"`
+		Action       string `json:"action"`
+		Name         string `json:"name"` // the new name, for rename
+	}
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+
+	var updated TournamentParticipant
+	_, err := updateTournament(r.Context(), req.TournamentID, func(t *Tournament) error {
+		p := t.participantByID(req.PlayerID)
+		if p == nil {
+			return errNotRegistered
+		}
+		var err error
+		if p.Name, p.Moderation, err = moderateName(p.Name, req.Action, req.Name); err != nil {
+			return err
+		}
+		updated = *p
+		return nil
+	})
+	switch {
+	case errors.Is(err, errNotRegistered):
+		http.Error(w, "Participant not found", http.StatusNotFound)
+		return
+	case errors.Is(err, errInvalidModeration), errors.Is(err, errInvalidName):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		writeTournamentError(w, r, req.TournamentID, err)
+		return
+	}
+	audit(r, "tournament.participant."+req.Action, req.TournamentID, map[string]any{
+		"playerId": updated.PlayerID,
+		"name":     updated.Name,
+	})
+
+	updated.Token = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
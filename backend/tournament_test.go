@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTournament returns a tournament with the given players registered and
+// its rounds an hour apart after registration
+func testTournament(format string, rounds int, players ...string) *Tournament {
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	t := &Tournament{
+		ID:                 "t1",
+		Name:               "Spring Cup",
+		Format:             format,
+		RegistrationOpens:  start,
+		RegistrationCloses: start.Add(time.Hour),
+	}
+	for i := 0; i < rounds; i++ {
+		t.Rounds = append(t.Rounds, TournamentRound{
+			Number:   i + 1,
+			StartsAt: start.Add(time.Duration(i+1) * time.Hour),
+			EndsAt:   start.Add(time.Duration(i+2) * time.Hour),
+			Seed:     int64(i + 1),
+		})
+	}
+	for _, p := range players {
+		t.Participants = append(t.Participants, TournamentParticipant{PlayerID: p, Name: strings.ToUpper(p), Token: "token-" + p})
+	}
+	return t
+}
+
+// finishedRuns returns a finished run for each player with their score
+func finishedRuns(scores map[string]int) map[string]TournamentRun {
+	out := make(map[string]TournamentRun, len(scores))
+	for p, s := range scores {
+		out[p] = TournamentRun{PlayerID: p, Score: s, Finished: true}
+	}
+	return out
+}
+
+func TestTournamentValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *Tournament)
+		ok     bool
+	}{
+		{"valid", func(t *Tournament) {}, true},
+		{"no name", func(t *Tournament) { t.Name = "" }, false},
+		{"long name", func(t *Tournament) { t.Name = strings.Repeat("x", maxTournamentName+1) }, false},
+		{"unknown format", func(t *Tournament) { t.Format = "swiss" }, false},
+		{"registration closes first", func(t *Tournament) { t.RegistrationCloses = t.RegistrationOpens }, false},
+		{"no rounds", func(t *Tournament) { t.Rounds = nil }, false},
+		{"round during registration", func(t *Tournament) { t.Rounds[0].StartsAt = t.RegistrationOpens }, false},
+		{"overlapping rounds", func(t *Tournament) { t.Rounds[1].StartsAt = t.Rounds[0].StartsAt }, false},
+		{"empty round", func(t *Tournament) { t.Rounds[1].EndsAt = t.Rounds[1].StartsAt }, false},
+	}
+	for _, tt := range tests {
+		tour := testTournament(formatPoints, 2)
+		tt.change(tour)
+		if err := tour.validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: validate() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestTournamentStatus(t *testing.T) {
+	tour := testTournament(formatPoints, 2, "a")
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{tour.RegistrationOpens.Add(-time.Minute), tournamentUpcoming},
+		{tour.RegistrationOpens, tournamentRegistration},
+		{tour.Rounds[0].StartsAt, tournamentRunning},
+		{tour.Rounds[1].EndsAt.Add(time.Hour), tournamentRunning}, // until the rounds are settled
+	}
+	for _, tt := range tests {
+		if got := tour.status(tt.at); got != tt.want {
+			t.Errorf("status at %v = %s, want %s", tt.at, got, tt.want)
+		}
+	}
+	if r := tour.currentRound(tour.Rounds[1].StartsAt.Add(time.Minute)); r == nil || r.Number != 2 {
+		t.Errorf("current round = %+v, want round 2", r)
+	}
+	if r := tour.unsettledRound(tour.Rounds[0].EndsAt); r == nil || r.Number != 1 {
+		t.Errorf("unsettled round = %+v, want round 1", r)
+	}
+
+	tour.settleRound(&tour.Rounds[0], nil)
+	tour.settleRound(&tour.Rounds[1], nil)
+	if got := tour.status(tour.Rounds[1].EndsAt); got != tournamentFinished {
+		t.Errorf("status after the last round = %s, want finished", got)
+	}
+}
+
+func TestSettleRoundPoints(t *testing.T) {
+	tour := testTournament(formatPoints, 2, "a", "b", "c", "d")
+	tour.settleRound(&tour.Rounds[0], finishedRuns(map[string]int{"a": 100, "b": 300, "c": 200}))
+	tour.settleRound(&tour.Rounds[1], finishedRuns(map[string]int{"a": 500, "b": 400, "c": 50, "d": 600}))
+
+	want := []TournamentRoundResult{
+		{Rank: 1, PlayerID: "b", Score: 300, Played: true, Points: 4},
+		{Rank: 2, PlayerID: "c", Score: 200, Played: true, Points: 3},
+		{Rank: 3, PlayerID: "a", Score: 100, Played: true, Points: 2},
+		{Rank: 4, PlayerID: "d"}, // didn't play
+	}
+	if fmt.Sprint(tour.Rounds[0].Results) != fmt.Sprint(want) {
+		t.Errorf("round 1 = %+v, want %+v", tour.Rounds[0].Results, want)
+	}
+
+	// By points, then total score: c and d both have 4, but d scored 600
+	var got []string
+	for _, s := range tour.standings() {
+		got = append(got, fmt.Sprintf("%s:%d", s.PlayerID, s.Points))
+	}
+	if want := "[b:6 a:5 d:4 c:4]"; fmt.Sprint(got) != want {
+		t.Errorf("standings = %v, want %s", got, want)
+	}
+}
+
+func TestSettleRoundElimination(t *testing.T) {
+	tour := testTournament(formatElimination, 2, "a", "b", "c", "d", "e")
+	tour.settleRound(&tour.Rounds[0], finishedRuns(map[string]int{"a": 100, "b": 500, "c": 400, "d": 300}))
+	if eligible := tour.eligible(2); len(eligible) != 3 {
+		t.Fatalf("%d players advanced, want 3 (half of 5, rounded up)", len(eligible))
+	}
+	if p := tour.participantByID("e"); p.EliminatedIn != 1 {
+		t.Errorf("e didn't play and wasn't eliminated")
+	}
+	tour.settleRound(&tour.Rounds[1], finishedRuns(map[string]int{"c": 900, "d": 800}))
+
+	var got []string
+	for _, s := range tour.standings() {
+		got = append(got, fmt.Sprintf("%s:%d", s.PlayerID, s.EliminatedIn))
+	}
+	// Still in, then those who lasted longer, then by total score
+	if want := "[c:0 d:0 b:2 a:1 e:1]"; fmt.Sprint(got) != want {
+		t.Errorf("standings = %v, want %s", got, want)
+	}
+}
+
+func TestTournamentPublic(t *testing.T) {
+	tour := testTournament(formatPoints, 2, "a", "b")
+	tour.Participants[1].Moderation = moderationPending
+	tour.settleRound(&tour.Rounds[0], nil)
+
+	pub := tour.public(tour.Rounds[1].StartsAt)
+	for _, p := range pub.Participants {
+		if p.Token != "" {
+			t.Errorf("public tournament shows %s's token", p.PlayerID)
+		}
+	}
+	if pub.Participants[1].Name == "B" {
+		t.Error("public tournament shows a name held for review")
+	}
+	if pub.Rounds[0].Seed == 0 || pub.Rounds[1].Seed != 0 {
+		t.Errorf("seeds shown: %d, %d; want only the settled round's", pub.Rounds[0].Seed, pub.Rounds[1].Seed)
+	}
+	if tour.Participants[0].Token == "" || tour.Rounds[1].Seed == 0 {
+		t.Error("public modified the tournament")
+	}
+}
+
+func TestTournamentHandlers(t *testing.T) {
+	post := func(h http.HandlerFunc, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(data))))
+		return rec
+	}
+	now := time.Now()
+	def := map[string]any{
+		"name":               "Test Cup",
+		"format":             formatPoints,
+		"registrationCloses": now.Add(time.Hour),
+		"rounds": []map[string]any{
+			{"startsAt": now.Add(2 * time.Hour), "endsAt": now.Add(3 * time.Hour)},
+		},
+	}
+	rec := post(createTournamentHandler, def)
+	var created struct {
+		Tournament Tournament `json:"tournament"`
+	}
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&created) != nil {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+	id := created.Tournament.ID
+	if created.Tournament.Status != tournamentRegistration || created.Tournament.Rounds[0].Seed != 0 {
+		t.Errorf("created tournament = %+v", created.Tournament)
+	}
+
+	rec = post(registerTournamentHandler, tournamentRequest{TournamentID: id, PlayerID: "p1", Name: "Alice"})
+	var registered struct {
+		You TournamentParticipant `json:"you"`
+	}
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&registered) != nil || registered.You.Token == "" {
+		t.Fatalf("register: %d %s", rec.Code, rec.Body)
+	}
+	if rec := post(registerTournamentHandler, tournamentRequest{TournamentID: id, PlayerID: "p1", Name: "Alice"}); rec.Code != http.StatusConflict {
+		t.Errorf("registering twice: %d, want 409", rec.Code)
+	}
+	if rec := post(registerTournamentHandler, tournamentRequest{TournamentID: "nope", PlayerID: "p2", Name: "Bob"}); rec.Code != http.StatusNotFound {
+		t.Errorf("registering for an unknown tournament: %d, want 404", rec.Code)
+	}
+	if rec := post(playTournamentHandler, tournamentRequest{TournamentID: id, Token: registered.You.Token}); rec.Code != http.StatusConflict {
+		t.Errorf("playing before the round: %d, want 409", rec.Code)
+	}
+	if rec := post(playTournamentHandler, tournamentRequest{TournamentID: id, Token: "forged"}); rec.Code != http.StatusForbidden {
+		t.Errorf("playing with a wrong token: %d, want 403", rec.Code)
+	}
+
+	// Once the round is over, whoever looks next settles it
+	ctx := context.Background()
+	if _, err := updateTournament(ctx, id, func(t *Tournament) error {
+		t.RegistrationCloses = now.Add(-2 * time.Hour)
+		t.Rounds[0].StartsAt, t.Rounds[0].EndsAt = now.Add(-time.Hour), now.Add(-time.Minute)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveRecord(ctx, tournamentRunKey(id, 1, "p1"), TournamentRun{PlayerID: "p1", Score: 2048, Finished: true}, 0); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	tournamentResultsHandler(rec, httptest.NewRequest(http.MethodGet, "/?id="+id, nil))
+	var results struct {
+		Status    string               `json:"status"`
+		Standings []TournamentStanding `json:"standings"`
+		Rounds    []TournamentRound    `json:"rounds"`
+	}
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&results) != nil {
+		t.Fatalf("results: %d %s", rec.Code, rec.Body)
+	}
+	if results.Status != tournamentFinished || len(results.Rounds) != 1 || results.Rounds[0].Seed == 0 {
+		t.Errorf("results: status %s, rounds %+v", results.Status, results.Rounds)
+	}
+	if len(results.Standings) != 1 || results.Standings[0].Points != 1 || results.Standings[0].TotalScore != 2048 {
+		t.Errorf("standings = %+v", results.Standings)
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	errWriteQueueFull   = errors.New("leaderboard write queue is full")
+	errWriteQueueClosed = errors.New("leaderboard write queue is closed")
+)
+
+// writeQueueMetrics are published on /debug/vars under "leaderboardWriteQueue"
+var writeQueueMetrics = expvar.NewMap("leaderboardWriteQueue")
+
+// leaderboardQueue persists accepted scores to DynamoDB in the background.
+// It is nil when DynamoDB isn't configured.
+var leaderboardQueue *writeQueue
+
+// queuedWrite is a leaderboard entry waiting to be written, along with the
+// (detached) context of the request that submitted it
+type queuedWrite struct {
+	ctx   context.Context
+	entry LeaderboardEntry
+}
+
+// writeQueue is a bounded, retrying write-behind queue. Every entry is
+// journaled to disk before it is accepted, so a crash or a DynamoDB outage
+// doesn't lose scores that were acknowledged to the player.
+type writeQueue struct {
+	mu      sync.Mutex
+	closed  bool
+	items   chan queuedWrite
+	journal *writeJournal
+	write   func(context.Context, LeaderboardEntry) error
+
+	workers int
+	// Once an entry has failed alertAttempts times, its failures are logged
+	// as errors rather than warnings
+	alertAttempts int
+	baseBackoff   time.Duration
+	maxBackoff    time.Duration
+
+	wg   sync.WaitGroup
+	stop chan struct{} // closed when draining gives up
+}
+
+// initWriteQueue creates the leaderboard write queue and replays any entries
+// left in the journal by a previous run
+func initWriteQueue() {
+	if dynamodbClient == nil {
+		slog.Info("DynamoDB not configured, leaderboard write queue disabled")
+		return
+	}
+
+	path := os.Getenv("WRITE_QUEUE_JOURNAL")
+	if path == "" {
+		path = "data/leaderboard-journal.jsonl"
+	}
+
+	journal, replay, err := openWriteJournal(path)
+	if err != nil {
+		slog.Error("failed to open leaderboard write journal", "path", path, "error", err)
+		os.Exit(1)
+	}
+
+	size := intFromEnv("WRITE_QUEUE_SIZE", 1000)
+	if size < len(replay) {
+		size = len(replay)
+	}
+
+	q := &writeQueue{
+		items:         make(chan queuedWrite, size),
+		journal:       journal,
+		write:         globalLeaderboard.saveEntryToDynamoDB,
+		workers:       intFromEnv("WRITE_QUEUE_WORKERS", 2),
+		alertAttempts: intFromEnv("WRITE_QUEUE_ALERT_ATTEMPTS", 10),
+		baseBackoff:   durationFromEnv("WRITE_QUEUE_BASE_BACKOFF", 200*time.Millisecond),
+		maxBackoff:    durationFromEnv("WRITE_QUEUE_MAX_BACKOFF", 30*time.Second),
+		stop:          make(chan struct{}),
+	}
+
+	writeQueueMetrics.Set("depth", expvar.Func(func() any { return len(q.items) }))
+	writeQueueMetrics.Set("capacity", expvar.Func(func() any { return cap(q.items) }))
+
+	// Entries from the journal were already acknowledged, so they go to the
+	// front of the queue and back into the in-memory leaderboard
+	for _, entry := range replay {
+		q.items <- queuedWrite{ctx: context.Background(), entry: entry}
+		globalLeaderboard.restoreEntry(entry)
+		writeQueueMetrics.Add("replayed", 1)
+	}
+	if len(replay) > 0 {
+		slog.Info("replaying leaderboard writes from journal", "entries", len(replay), "path", path)
+	}
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.run()
+	}
+
+	leaderboardQueue = q
+	slog.Info("leaderboard write queue started", "capacity", size, "workers", q.workers, "journal", path)
+}
+
+// Enqueue journals entry and schedules it for writing. Once it returns nil the
+// entry will eventually be written, even across restarts.
+func (q *writeQueue) Enqueue(ctx context.Context, entry LeaderboardEntry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return errWriteQueueClosed
+	}
+	if len(q.items) == cap(q.items) {
+		writeQueueMetrics.Add("rejected", 1)
+		return errWriteQueueFull
+	}
+
+	if err := q.journal.Append(entry); err != nil {
+		writeQueueMetrics.Add("journalErrors", 1)
+		return fmt.Errorf("failed to journal leaderboard entry: %w", err)
+	}
+
+	// Can't block: we hold mu and checked there is room
+	q.items <- queuedWrite{ctx: context.WithoutCancel(ctx), entry: entry}
+	writeQueueMetrics.Add("enqueued", 1)
+	return nil
+}
+
+// Pending returns the accepted entries that haven't been written to DynamoDB
+// yet, including any still being retried
+func (q *writeQueue) Pending() []LeaderboardEntry {
+	return q.journal.Pending()
+}
+
+// run writes queued entries until the queue is closed and empty
+func (q *writeQueue) run() {
+	defer q.wg.Done()
+
+	for item := range q.items {
+		select {
+		case <-q.stop:
+			// Draining gave up; leave the rest for the next start
+			writeQueueMetrics.Add("deferred", 1)
+			continue
+		default:
+		}
+
+		if !q.writeWithRetry(item) {
+			continue
+		}
+		if err := q.journal.Ack(item.entry.ID); err != nil {
+			// The entry will be written again on the next start, which is harmless
+			writeQueueMetrics.Add("journalErrors", 1)
+			loggerFromContext(item.ctx).Error("failed to acknowledge journaled entry", "entry_id", item.entry.ID, "error", err)
+		}
+	}
+}
+
+// writeWithRetry keeps trying to write item, backing off exponentially up to
+// maxBackoff between attempts. It only gives up when draining does, returning
+// false with the entry left in the journal for the next start.
+func (q *writeQueue) writeWithRetry(item queuedWrite) bool {
+	logger := loggerFromContext(item.ctx)
+	backoff := q.baseBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := q.write(item.ctx, item.entry)
+		if err == nil {
+			writeQueueMetrics.Add("written", 1)
+			return true
+		}
+
+		writeQueueMetrics.Add("failures", 1)
+		if attempt >= q.alertAttempts {
+			if attempt == q.alertAttempts {
+				writeQueueMetrics.Add("stalled", 1)
+			}
+			logger.Error("leaderboard write still failing, retrying",
+				"entry_id", item.entry.ID, "attempt", attempt, "backoff", backoff, "error", err)
+		} else {
+			logger.Warn("leaderboard write failed, retrying",
+				"entry_id", item.entry.ID, "attempt", attempt, "backoff", backoff, "error", err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-q.stop:
+			writeQueueMetrics.Add("deferred", 1)
+			return false
+		}
+
+		backoff *= 2
+		if backoff > q.maxBackoff {
+			backoff = q.maxBackoff
+		}
+	}
+}
+
+// Drain stops accepting entries and waits for queued ones to be written. If
+// ctx expires first, retries are abandoned and the remaining entries stay in
+// the journal to be replayed on the next start.
+func (q *writeQueue) Drain(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.items)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		close(q.stop)
+		<-done
+	}
+
+	if closeErr := q.journal.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// journalRecord is one line of the write journal
+type journalRecord struct {
+	Op    string            `json:"op"` // "add" or "ack"
+	ID    string            `json:"id"`
+	Entry *LeaderboardEntry `json:"entry,omitempty"`
+}
+
+// writeJournal is an append-only JSON lines file of accepted and written
+// entries. It is truncated whenever nothing is outstanding.
+type writeJournal struct {
+	mu      sync.Mutex
+	file    *os.File
+	pending map[string]LeaderboardEntry // accepted but not yet written
+}
+
+// openWriteJournal opens (or creates) the journal at path and returns the
+// entries that were accepted but never acknowledged
+func openWriteJournal(path string) (*writeJournal, []LeaderboardEntry, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, err
+	}
+
+	order, entries, err := readWriteJournal(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Rewrite the journal so it only holds what is still outstanding. The new
+	// copy is synced and renamed into place so a crash here loses nothing.
+	tmpPath := path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	j := &writeJournal{file: tmp, pending: make(map[string]LeaderboardEntry)}
+	var replay []LeaderboardEntry
+	for _, id := range order {
+		entry, ok := entries[id]
+		if !ok {
+			continue
+		}
+		if err := j.Append(entry); err != nil {
+			tmp.Close()
+			return nil, nil, err
+		}
+		replay = append(replay, entry)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return nil, nil, err
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		return nil, nil, err
+	}
+
+	j.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return j, replay, nil
+}
+
+// syncDir flushes dir, so a rename into it survives a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+	return d.Close()
+}
+
+// readWriteJournal returns the IDs of entries added to the journal at path, in
+// order, along with the ones that were never acknowledged
+func readWriteJournal(path string) ([]string, map[string]LeaderboardEntry, error) {
+	entries := make(map[string]LeaderboardEntry)
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, entries, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var order []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// A torn write from a crash can only affect the last line
+			slog.Warn("skipping corrupt journal line", "path", path, "error", err)
+			continue
+		}
+		switch rec.Op {
+		case "add":
+			if rec.Entry != nil {
+				if _, seen := entries[rec.ID]; !seen {
+					order = append(order, rec.ID)
+				}
+				entries[rec.ID] = *rec.Entry
+			}
+		case "ack":
+			delete(entries, rec.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return order, entries, nil
+}
+
+// Append durably records entry as accepted
+func (j *writeJournal) Append(entry LeaderboardEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.writeRecord(journalRecord{Op: "add", ID: entry.ID, Entry: &entry}); err != nil {
+		return err
+	}
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+	j.pending[entry.ID] = entry
+	return nil
+}
+
+// Pending returns the entries accepted but not yet written
+func (j *writeJournal) Pending() []LeaderboardEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries := make([]LeaderboardEntry, 0, len(j.pending))
+	for _, entry := range j.pending {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Ack records that the entry with id has been written to DynamoDB
+func (j *writeJournal) Ack(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	delete(j.pending, id)
+	if len(j.pending) == 0 {
+		return j.file.Truncate(0)
+	}
+	return j.writeRecord(journalRecord{Op: "ack", ID: id})
+}
+
+func (j *writeJournal) writeRecord(rec journalRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close flushes and closes the journal file
+func (j *writeJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.file.Sync(); err != nil {
+		j.file.Close()
+		return err
+	}
+	return j.file.Close()
+}
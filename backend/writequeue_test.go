@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startTestWriteQueue starts a queue of size entries that writes with write
+// and journals to path, returning it with the entries it replayed
+func startTestWriteQueue(t *testing.T, path string, size int, write func(context.Context, LeaderboardEntry) error) (*writeQueue, []LeaderboardEntry) {
+	t.Helper()
+	journal, replay, err := openWriteJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &writeQueue{
+		items:         make(chan queuedWrite, max(size, len(replay))),
+		journal:       journal,
+		write:         write,
+		workers:       1,
+		alertAttempts: 3,
+		baseBackoff:   time.Millisecond,
+		maxBackoff:    5 * time.Millisecond,
+		stop:          make(chan struct{}),
+	}
+	for _, entry := range replay {
+		q.items <- queuedWrite{ctx: context.Background(), entry: entry}
+	}
+	q.wg.Add(1)
+	go q.run()
+	return q, replay
+}
+
+func TestWriteJournalReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, replay, err := openWriteJournal(path)
+	if err != nil || len(replay) != 0 {
+		t.Fatalf("new journal: %v, %v", replay, err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := j.Append(LeaderboardEntry{ID: id, Score: 100}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Ack("b"); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	// A crash part way through a write leaves a torn last line
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"add","id":"d","ent`)
+	f.Close()
+
+	j, replay, err = openWriteJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalIDs(replay, []LeaderboardEntry{{ID: "a"}, {ID: "c"}}) {
+		t.Errorf("replayed %v, want [a c]", ids(replay))
+	}
+	if _, err := os.Stat(path + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temporary journal left behind: %v", err)
+	}
+
+	// Once everything is written the journal is emptied
+	j.Ack("a")
+	j.Ack("c")
+	j.Close()
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Errorf("journal with nothing pending: %v, %v", info, err)
+	}
+}
+
+func TestWriteQueueRetries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	q, _ := startTestWriteQueue(t, path, 10, func(ctx context.Context, e LeaderboardEntry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[e.ID]++
+		if attempts[e.ID] < 3 {
+			return errors.New("throttled")
+		}
+		return nil
+	})
+
+	for _, id := range []string{"a", "b"} {
+		if err := q.Enqueue(context.Background(), LeaderboardEntry{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if attempts["a"] != 3 || attempts["b"] != 3 {
+		t.Errorf("attempts = %v, want 3 each", attempts)
+	}
+	if err := q.Enqueue(context.Background(), LeaderboardEntry{ID: "late"}); !errors.Is(err, errWriteQueueClosed) {
+		t.Errorf("enqueue after drain: %v, want errWriteQueueClosed", err)
+	}
+	if _, replay, _ := openWriteJournal(path); len(replay) != 0 {
+		t.Errorf("written entries replayed: %v", ids(replay))
+	}
+}
+
+func TestWriteQueueOutage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	release := make(chan struct{})
+	tried := make(chan struct{}, 1)
+	q, _ := startTestWriteQueue(t, path, 2, func(ctx context.Context, e LeaderboardEntry) error {
+		select {
+		case tried <- struct{}{}:
+		default:
+		}
+		select {
+		case <-release:
+			return nil
+		default:
+			return errors.New("unavailable")
+		}
+	})
+
+	// One entry is being retried and two wait behind it, filling the queue
+	var accepted []LeaderboardEntry
+	for _, id := range []string{"a", "b", "c", "d"} {
+		err := q.Enqueue(context.Background(), LeaderboardEntry{ID: id})
+		if errors.Is(err, errWriteQueueFull) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		accepted = append(accepted, LeaderboardEntry{ID: id})
+		if id == "a" {
+			<-tried // the worker has taken it off the queue
+		}
+	}
+	if len(accepted) != 3 {
+		t.Fatalf("accepted %v during the outage, want three", ids(accepted))
+	}
+	if pending := q.Pending(); len(pending) != 3 {
+		t.Errorf("pending = %v, want the accepted entries", ids(pending))
+	}
+
+	// Shutting down mid-outage keeps every accepted entry for the next start
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("drain during the outage: %v, want a deadline error", err)
+	}
+
+	close(release)
+	var written []LeaderboardEntry
+	var mu sync.Mutex
+	q, replay := startTestWriteQueue(t, path, 2, func(ctx context.Context, e LeaderboardEntry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		written = append(written, e)
+		return nil
+	})
+	if !equalIDs(replay, accepted) {
+		t.Errorf("replayed %v, want %v", ids(replay), ids(accepted))
+	}
+	if err := q.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !equalIDs(written, accepted) {
+		t.Errorf("written after restart %v, want %v", ids(written), ids(accepted))
+	}
+}
@@ -13,6 +13,8 @@ export default function Game2048() {
   });
   const [gameOver, setGameOver] = useState(false);
   const [won, setWon] = useState(false);
+  const [spectators, setSpectators] = useState(0);
+  const [achievementToast, setAchievementToast] = useState(null);
   const [lastBoard, setLastBoard] = useState([]);
   const [error, setError] = useState(null);
   const [newTiles, setNewTiles] = useState([]);
@@ -120,7 +122,7 @@ export default function Game2048() {
       setLoading(true);
       setError(null);
       console.log('Starting new game...');
-      const res = await axios.post(`${API}/game/new`);
+      const res = await axios.post(`${API}/game/new`, { playerId: getPlayerId() });
       console.log('New game response:', res.data);
       setGameId(res.data.id);
       gameIdRef.current = res.data.id;
@@ -131,6 +133,7 @@ export default function Game2048() {
       setGameOver(false);
       gameOverRef.current = false;
       setWon(false);
+      setSpectators(0);
       setNewTiles([]);
       setGameStartTime(Date.now());
       setMoveCount(0);
@@ -146,7 +149,7 @@ export default function Game2048() {
   // Leaderboard functions
   const fetchLeaderboard = async () => {
     try {
-      const res = await axios.get(`${API}/leaderboard/top?limit=10`);
+      const res = await axios.get(`${API}/leaderboard/top?limit=10&mode=classic&view=best`);
       setLeaderboardData(res.data.scores || []);
     } catch (err) {
       console.error("Error fetching leaderboard:", err);
@@ -157,14 +160,11 @@ export default function Game2048() {
     if (!playerName.trim() || score === 0) return;
 
     const duration = gameStartTime ? Math.floor((Date.now() - gameStartTime) / 1000) : 0;
-    const playerId = localStorage.getItem('2048-player-id') || generatePlayerId();
-    
-    if (!localStorage.getItem('2048-player-id')) {
-      localStorage.setItem('2048-player-id', playerId);
-    }
+    const playerId = getPlayerId();
 
     try {
       await axios.post(`${API}/leaderboard/submit`, {
+        gameId: gameIdRef.current,
         playerId,
         name: playerName.trim(),
         score,
@@ -185,6 +185,25 @@ export default function Game2048() {
     return 'player_' + Date.now() + '_' + Math.random().toString(36).substr(2, 9);
   };
 
+  const getPlayerId = () => {
+    let playerId = localStorage.getItem('2048-player-id');
+    if (!playerId) {
+      playerId = generatePlayerId();
+      localStorage.setItem('2048-player-id', playerId);
+    }
+    return playerId;
+  };
+
+  // Achievements earned by a move are toasted one after another
+  const showAchievements = (awards) => {
+    awards.forEach((award, i) => {
+      setTimeout(() => {
+        setAchievementToast(award);
+        setTimeout(() => setAchievementToast(current => current === award ? null : current), 4000);
+      }, i * 4000);
+    });
+  };
+
   const handleMove = async (dir) => {
     if (!gameIdRef.current || gameOverRef.current || loadingRef.current || isMoving) {
       console.log('Move blocked:', { gameId: gameIdRef.current, gameOver: gameOverRef.current, loading: loadingRef.current, isMoving });
@@ -205,6 +224,10 @@ export default function Game2048() {
       setGameOver(res.data.gameOver);
       gameOverRef.current = res.data.gameOver;
       setWon(res.data.won);
+      setSpectators(res.data.spectators || 0);
+      if (res.data.achievements) {
+        showAchievements(res.data.achievements);
+      }
 
       // Find only truly new tiles (spawned after move)
       const newTilesArr = [];
@@ -280,6 +303,12 @@ export default function Game2048() {
             <div className="score-label">BEST</div>
             <div className="score-value">{bestScore.toLocaleString()}</div>
           </div>
+          {spectators > 0 && (
+            <div className={`score-box ${isDarkMode ? 'dark' : ''}`}>
+              <div className="score-label">WATCHING</div>
+              <div className="score-value">{spectators.toLocaleString()}</div>
+            </div>
+          )}
         </div>
 
         {/* Game Status */}
@@ -299,6 +328,11 @@ export default function Game2048() {
               Loading...
             </div>
           )}
+          {achievementToast && (
+            <div className={`status-message achievement ${isDarkMode ? 'dark' : ''}`} role="status">
+              🏆 {achievementToast.name}: {achievementToast.description}
+            </div>
+          )}
           {error && (
             <div className={`status-message error ${isDarkMode ? 'dark' : ''}`}>
               {error}
@@ -310,6 +344,21 @@ export default function Game2048() {
         <div className={`game-board ${isDarkMode ? 'dark' : ''} ${isMoving ? 'moving' : ''}`}>
           {Array.isArray(board) && board.length > 0 ? (
             board.flat().map((cell, i) => {
+              // Obstacles are objects such as {type: "rock", hits: 2}
+              if (cell !== null && typeof cell === "object") {
+                const label = cell.type === "rock" ? `rock, ${cell.hits} left` : cell.type;
+                return (
+                  <div
+                    key={i}
+                    className={`game-tile tile-${cell.type} ${isDarkMode ? 'dark' : ''}`}
+                    role="gridcell"
+                    aria-label={label}
+                    title={label}
+                  >
+                    {cell.type === "rock" ? cell.hits : ""}
+                  </div>
+                );
+              }
               const value = cell !== 0 ? cell : "";
               const r = Math.floor(i / 4);
               const c = i % 4;
@@ -425,6 +474,7 @@ export default function Game2048() {
                           <div className="player-name">{entry.name}</div>
                           <div className="player-stats">
                             {entry.moves} moves • {Math.floor(entry.duration / 60)}:{(entry.duration % 60).toString().padStart(2, '0')}
+                            {entry.mode && entry.mode !== 'classic' && ` • ${entry.mode}`}
                           </div>
                         </div>
                         <div className="player-score">{entry.score.toLocaleString()}</div>
@@ -9,6 +9,10 @@ export default defineConfig({
       '/game': {
         target: 'http://backend:8000',
         changeOrigin: true
+      },
+      '/player': {
+        target: 'http://backend:8000',
+        changeOrigin: true
       }
     }
   }
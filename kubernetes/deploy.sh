@@ -63,7 +63,7 @@ print_status "Deploying MongoDB..."
 kubectl apply -f mongodb-deployment.yaml
 
 print_status "Deploying backend..."
-kubectl apply -f backend-deployment.yaml
+kubectl apply -f backend-statefulset.yaml
 kubectl apply -f backend-service.yaml
 
 print_status "Deploying frontend..."
@@ -79,7 +79,7 @@ kubectl apply -f hpa.yaml
 # Wait for deployments to be ready
 print_status "Waiting for deployments to be ready..."
 kubectl wait --for=condition=available --timeout=300s deployment/mongodb-deployment -n game-2048
-kubectl wait --for=condition=available --timeout=300s deployment/backend-deployment -n game-2048
+kubectl rollout status --timeout=300s statefulset/backend-statefulset -n game-2048
 kubectl wait --for=condition=available --timeout=300s deployment/frontend-deployment -n game-2048
 
 print_success "All deployments are ready!"
@@ -101,5 +101,5 @@ print_status "Or use port-forward: kubectl port-forward -n game-2048 svc/fronten
 echo ""
 print_status "Useful commands:"
 echo "  View logs: kubectl logs -n game-2048 -l app=2048-backend"
-echo "  Scale backend: kubectl scale -n game-2048 deployment/backend-deployment --replicas=3"
+echo "  Scale backend: kubectl scale -n game-2048 statefulset/backend-statefulset --replicas=3"
 echo "  Delete deployment: kubectl delete namespace game-2048"
\ No newline at end of file